@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/tag"
+)
+
+// annotation prefix in the doc comment of sql dao method
+const annotationPrefix = "gormcache:"
+
+// Config generator config
+type Config struct {
+	TypeName   string // sql dao type name
+	DoName     string // database object model type name
+	CacheName  string // generated cache dao type name
+	SQLDaoExpr string // expression to get sql dao instance
+	Prefix     string // object cache prefix
+}
+
+// Method cached method to generate
+type Method struct {
+	Name       string
+	HandleName string
+	MethodExpr string
+	TypeConst  string
+	Keys       []string
+	Args       []int
+	Params     []Param
+	List       bool
+	Paged      bool // range method, wrapped with page method too
+	Aggregate  bool
+	ResultType string // type of the first result
+}
+
+// Param method parameter
+type Param struct {
+	Name string
+	Type string
+}
+
+type templateData struct {
+	*Config
+	Package      string
+	Imports      []string
+	InstanceName string
+	Methods      []*Method
+}
+
+// Generate parse the package in dir and generate source of cached dao
+func Generate(dir string, config *Config) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		if findTypeSpec(p, config.TypeName) != nil {
+			pkg = p
+			break
+		}
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("type '%s' not found in %s", config.TypeName, dir)
+	}
+
+	// drop the files generated by us, otherwise regenerating will see the old output
+	files := make([]*ast.File, 0)
+	fileNames := make([]string, 0)
+	for name := range pkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	for _, name := range fileNames {
+		if !isGenerated(pkg.Files[name]) {
+			files = append(files, pkg.Files[name])
+		}
+	}
+
+	data := &templateData{
+		Config:       config,
+		Package:      pkg.Name,
+		InstanceName: lowerFirst(config.CacheName) + "Instance",
+	}
+	imports := make(map[string]string)
+	doFields := findStructFields(pkg, config.DoName)
+
+	if idx := strings.Index(config.DoName, "."); idx > 0 {
+		path, err := findImport(files, config.DoName[:idx])
+		if err != nil {
+			return nil, err
+		}
+		imports[config.DoName[:idx]] = path
+	}
+
+	errs := make([]string, 0)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Recv == nil || funcDecl.Doc == nil {
+				continue
+			}
+			recvName, pointer := receiverTypeName(funcDecl.Recv.List[0].Type)
+			if recvName != config.TypeName {
+				continue
+			}
+			annotation := findAnnotation(funcDecl.Doc)
+			if annotation == "" {
+				continue
+			}
+
+			method, err := parseMethod(fset, funcDecl, annotation, pointer, config, doFields)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", fset.Position(funcDecl.Pos()), err))
+				continue
+			}
+			err = collectImports(file, funcDecl.Type.Params, imports)
+			if err == nil && method.Aggregate {
+				err = collectImports(file, funcDecl.Type.Results, imports)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", fset.Position(funcDecl.Pos()), err))
+				continue
+			}
+			data.Methods = append(data.Methods, method)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	if len(data.Methods) == 0 {
+		return nil, fmt.Errorf("no method of '%s' is annotated with '%s'", config.TypeName, annotationPrefix)
+	}
+
+	for name, path := range imports {
+		if templateImports[path] && name == pathBase(path) {
+			continue
+		}
+		if name == pathBase(path) {
+			data.Imports = append(data.Imports, strconv.Quote(path))
+		} else {
+			data.Imports = append(data.Imports, name+" "+strconv.Quote(path))
+		}
+	}
+	sort.Strings(data.Imports)
+
+	buf := &bytes.Buffer{}
+	err = cacheDaoTemplate.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source failed: %v\n%s", err, buf.String())
+	}
+	return src, nil
+}
+
+// parseMethod parse and validate the annotated sql dao method
+func parseMethod(fset *token.FileSet, funcDecl *ast.FuncDecl, annotation string, pointer bool, config *Config, doFields map[string]bool) (*Method, error) {
+	notify, err := tag.ResolveNotifyTag(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation '%s': %v", annotation, err)
+	}
+
+	method := &Method{
+		Name:       funcDecl.Name.Name,
+		HandleName: lowerFirst(funcDecl.Name.Name) + "Method",
+		Keys:       notify.Keys,
+		Args:       notify.Args,
+	}
+	if pointer {
+		method.MethodExpr = fmt.Sprintf("(*%s).%s", config.TypeName, method.Name)
+	} else {
+		method.MethodExpr = fmt.Sprintf("%s.%s", config.TypeName, method.Name)
+	}
+	switch notify.Type {
+	case constant.NotifyTypeConcrete:
+		method.TypeConst = "constant.NotifyTypeConcrete"
+	case constant.NotifyTypeList:
+		method.TypeConst = "constant.NotifyTypeList"
+		method.List = true
+	case constant.NotifyTypeRange:
+		method.TypeConst = "constant.NotifyTypeRange"
+		method.List = true
+		method.Paged = true
+	case constant.NotifyTypeMulti:
+		method.TypeConst = "constant.NotifyTypeMulti"
+		method.List = true
+	case constant.NotifyTypeAggregate:
+		method.TypeConst = "constant.NotifyTypeAggregate"
+		method.Aggregate = true
+	default:
+		return nil, fmt.Errorf("unknown type '%s'", notify.Type)
+	}
+
+	// the first result is the cached value, with an optional trailing error
+	results := make([]string, 0)
+	if funcDecl.Type.Results != nil {
+		for _, field := range funcDecl.Type.Results.List {
+			typeStr, err := exprString(fset, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				results = append(results, typeStr)
+			}
+		}
+	}
+	if len(results) == 0 || results[0] == "error" {
+		return nil, errors.New("method should return the result as the first return value")
+	}
+	if len(results) > 1 && results[len(results)-1] != "error" {
+		return nil, fmt.Errorf("the last return value should be error, got '%s'", results[len(results)-1])
+	}
+	method.ResultType = results[0]
+
+	for i, field := range funcDecl.Type.Params.List {
+		typeStr, err := exprString(fset, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := field.Type.(*ast.Ellipsis); ok {
+			return nil, errors.New("variadic parameter is not supported")
+		}
+		if len(field.Names) == 0 {
+			method.Params = append(method.Params, Param{Name: fmt.Sprintf("arg%d", i), Type: typeStr})
+			continue
+		}
+		for _, name := range field.Names {
+			paramName := name.Name
+			if reservedParamNames[paramName] {
+				paramName = fmt.Sprintf("arg%d", len(method.Params))
+			}
+			method.Params = append(method.Params, Param{Name: paramName, Type: typeStr})
+		}
+	}
+
+	if len(notify.Keys) != len(notify.Args) {
+		return nil, fmt.Errorf("%d keys but %d args", len(notify.Keys), len(notify.Args))
+	}
+	for _, arg := range notify.Args {
+		if arg < 0 || arg >= len(method.Params) {
+			return nil, fmt.Errorf("arg index %d out of range, method has %d parameters", arg, len(method.Params))
+		}
+	}
+	if doFields != nil {
+		for _, key := range notify.Keys {
+			if !doFields[key] {
+				return nil, fmt.Errorf("key '%s' is not a field of '%s'", key, config.DoName)
+			}
+		}
+	}
+	if notify.Type == constant.NotifyTypeRange && (len(method.Params) == 0 || method.Params[0].Type != "*gorm.DB") {
+		return nil, errors.New("the first parameter of range method should be '*gorm.DB'")
+	}
+	return method, nil
+}
+
+// names of the generated wrapper receiver and parameters, which are renamed in sql dao parameters
+var reservedParamNames = map[string]bool{"_": true, "ctx": true, "dao": true, "offset": true, "limit": true}
+
+func (m *Method) ParamList() string {
+	params := make([]string, 0)
+	for _, p := range m.Params {
+		params = append(params, p.Name+" "+p.Type)
+	}
+	return strings.Join(params, ", ")
+}
+
+func (m *Method) CallArgs() string {
+	var sb strings.Builder
+	for _, p := range m.Params {
+		sb.WriteString(", " + p.Name)
+	}
+	return sb.String()
+}
+
+// ReturnType type of the generated wrapper result
+func (m *Method) ReturnType(doName string) string {
+	if m.Aggregate {
+		return m.ResultType
+	}
+	if m.List {
+		return "[]" + doName
+	}
+	return "*" + doName
+}
+
+// Converter core helper that converts the untyped result of cached method
+func (m *Method) Converter(doName string) string {
+	if m.Aggregate {
+		return "ToValue[" + m.ResultType + "]"
+	}
+	if m.List {
+		return "ToList[" + doName + "]"
+	}
+	return "ToObject[" + doName + "]"
+}
+
+func (m *Method) KeysLiteral() string {
+	keys := make([]string, 0)
+	for _, k := range m.Keys {
+		keys = append(keys, strconv.Quote(k))
+	}
+	return strings.Join(keys, ", ")
+}
+
+func (m *Method) ArgsLiteral() string {
+	args := make([]string, 0)
+	for _, a := range m.Args {
+		args = append(args, strconv.Itoa(a))
+	}
+	return strings.Join(args, ", ")
+}
+
+func findTypeSpec(pkg *ast.Package, name string) *ast.TypeSpec {
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.Name == name {
+					return typeSpec
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findStructFields get field names of struct in the package, nil if the struct is not in this package
+func findStructFields(pkg *ast.Package, name string) map[string]bool {
+	typeSpec := findTypeSpec(pkg, name)
+	if typeSpec == nil {
+		return nil
+	}
+	structType, ok := typeSpec.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+	fields := make(map[string]bool)
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			// embedded struct like 'gorm.Model', we can't see its fields
+			return nil
+		}
+		for _, n := range field.Names {
+			fields[n.Name] = true
+		}
+	}
+	return fields
+}
+
+func receiverTypeName(expr ast.Expr) (string, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		if ident, ok := star.X.(*ast.Ident); ok {
+			return ident.Name, true
+		}
+		return "", true
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, false
+	}
+	return "", false
+}
+
+func findAnnotation(doc *ast.CommentGroup) string {
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(text, annotationPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(text, annotationPrefix))
+		}
+	}
+	return ""
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if strings.HasPrefix(comment.Text, "// Code generated by gormcache-gen") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectImports collect the imports referenced by parameter types
+func collectImports(file *ast.File, params *ast.FieldList, imports map[string]string) error {
+	var err error
+	ast.Inspect(params, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := selector.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path, findErr := findImport([]*ast.File{file}, ident.Name)
+		if findErr != nil {
+			err = findErr
+			return false
+		}
+		imports[ident.Name] = path
+		return false
+	})
+	return err
+}
+
+func findImport(files []*ast.File, name string) (string, error) {
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if spec.Name != nil && spec.Name.Name == name {
+				return path, nil
+			}
+			if spec.Name == nil && pathBase(path) == name {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("import of package '%s' not found", name)
+}
+
+// pathBase package name guessed from import path, like 'gorm' of 'gorm.io/gorm' and 'redis' of '.../redis/v8'
+func pathBase(path string) string {
+	parts := strings.Split(path, "/")
+	last := parts[len(parts)-1]
+	if len(parts) > 1 && strings.HasPrefix(last, "v") {
+		if _, err := strconv.Atoi(last[1:]); err == nil {
+			last = parts[len(parts)-2]
+		}
+	}
+	return last
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) (string, error) {
+	buf := &bytes.Buffer{}
+	err := printer.Fprint(buf, fset, expr)
+	return buf.String(), err
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// imports in cacheDaoTemplate, not added again for parameter types
+var templateImports = map[string]bool{
+	"context":                               true,
+	"sync":                                  true,
+	"github.com/zhyeah/gorm-cache/constant": true,
+	"github.com/zhyeah/gorm-cache/core":     true,
+}
+
+var cacheDaoTemplate = template.Must(template.New("cachedao").Parse(`// Code generated by gormcache-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/core"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+// {{.CacheName}} cached dao of {{.TypeName}}
+type {{.CacheName}} struct {
+	core.CacheDaoBase
+{{range .Methods}}
+	{{.HandleName}} *core.CachedMethod
+{{- end}}
+}
+
+var (
+	{{.InstanceName}}     *{{.CacheName}}
+	{{.InstanceName}}Once sync.Once
+)
+
+func init() {
+	core.CacheDaoMap["{{.CacheName}}"] = func() interface{} {
+		return Get{{.CacheName}}()
+	}
+}
+
+// Get{{.CacheName}} get the singleton of {{.CacheName}}
+func Get{{.CacheName}}() *{{.CacheName}} {
+	{{.InstanceName}}Once.Do(func() {
+		dao := &{{.CacheName}}{}
+		dao.Do = &{{.DoName}}{}
+		dao.SQLDao = {{.SQLDaoExpr}}
+{{- if .Prefix}}
+		dao.ObjectCachePrefix = {{printf "%q" .Prefix}}
+{{- end}}
+{{range .Methods}}
+		dao.{{.HandleName}} = dao.MustBindMethod(&core.MethodBinding{
+			Method: {{.MethodExpr}},
+			Type:   {{.TypeConst}},
+			Keys:   []string{ {{- .KeysLiteral -}} },
+			Args:   []int{ {{- .ArgsLiteral -}} },
+		})
+{{- end}}
+
+		{{.InstanceName}} = dao
+	})
+	return {{.InstanceName}}
+}
+{{range .Methods}}
+// {{.Name}} cached '{{$.TypeName}}.{{.Name}}'
+func (dao *{{$.CacheName}}) {{.Name}}({{.ParamList}}) ({{.ReturnType $.DoName}}, error) {
+	return dao.{{.Name}}Context(context.Background(){{.CallArgs}})
+}
+
+// {{.Name}}Context cached '{{$.TypeName}}.{{.Name}}', with context
+func (dao *{{$.CacheName}}) {{.Name}}Context(ctx context.Context{{if .Params}}, {{.ParamList}}{{end}}) ({{.ReturnType $.DoName}}, error) {
+	return core.{{.Converter $.DoName}}(dao.{{.HandleName}}.GetContext(ctx{{.CallArgs}}))
+}
+{{- if .Paged}}
+
+// {{.Name}}Page cached page [offset, offset+limit) of '{{$.TypeName}}.{{.Name}}', with the total count
+func (dao *{{$.CacheName}}) {{.Name}}Page(offset, limit int{{if .Params}}, {{.ParamList}}{{end}}) ([]{{$.DoName}}, int64, error) {
+	return dao.{{.Name}}PageContext(context.Background(), offset, limit{{.CallArgs}})
+}
+
+// {{.Name}}PageContext cached page [offset, offset+limit) of '{{$.TypeName}}.{{.Name}}', with the total count and context
+func (dao *{{$.CacheName}}) {{.Name}}PageContext(ctx context.Context, offset, limit int{{if .Params}}, {{.ParamList}}{{end}}) ([]{{$.DoName}}, int64, error) {
+	return core.ToPage[{{$.DoName}}](dao.{{.HandleName}}.GetPageContext(ctx, offset, limit{{.CallArgs}}))
+}
+{{- end}}
+{{end}}`))
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files of generator tests")
+
+// packages of testdata imported by the generated sources, type checked from the dir
+var testdataPackages = map[string]string{
+	"example.com/app/model": "testdata/qualified/model",
+}
+
+func TestGenerate(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			// concrete, list, range (with page), aggregate and multi methods, renamed parameters
+			name: "basic",
+			config: &Config{
+				TypeName:   "UserSQLDao",
+				DoName:     "UserDo",
+				CacheName:  "UserCacheDao",
+				SQLDaoExpr: "&UserSQLDao{}",
+			},
+		},
+		{
+			// Do of another package, value receiver, sql dao got by function and object cache prefix
+			name: "qualified",
+			config: &Config{
+				TypeName:   "UserDao",
+				DoName:     "model.UserDo",
+				CacheName:  "UserCacheDao",
+				SQLDaoExpr: "NewUserDao(nil)",
+				Prefix:     "app",
+			},
+		},
+	}
+
+	// packages imported from source are shared by cases
+	fset := token.NewFileSet()
+	imp := newTestdataImporter(fset)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := filepath.Join("testdata", c.name)
+			src, err := Generate(dir, c.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, err := format.Source(src)
+			if err != nil {
+				t.Fatalf("generated source can't be formatted: %v", err)
+			}
+			if !bytes.Equal(formatted, src) {
+				t.Fatal("generated source is not gofmt-ed")
+			}
+
+			typeCheck(t, fset, imp, dir, src)
+
+			golden := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				err = ioutil.WriteFile(golden, src, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(src, want) {
+				t.Fatalf("generated source differs from %s, run with -update if it's expected:\n%s", golden, src)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := []struct {
+		config *Config
+		err    string
+	}{
+		{&Config{TypeName: "NoSuchDao", DoName: "UserDo"}, "type 'NoSuchDao' not found"},
+		{&Config{TypeName: "UserDo", DoName: "UserDo"}, "no method of 'UserDo' is annotated"},
+	}
+	for _, c := range cases {
+		_, err := Generate(filepath.Join("testdata", "basic"), c.config)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Generate %s: got error %v, want %q", c.config.TypeName, err, c.err)
+		}
+	}
+}
+
+// typeCheck type check the generated source with the sources in dir, as one package
+func typeCheck(t *testing.T, fset *token.FileSet, imp types.Importer, dir string, src []byte) {
+	t.Helper()
+	files, err := parseDir(fset, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "generated.go"), src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, file)
+
+	config := &types.Config{Importer: imp}
+	_, err = config.Check(files[0].Name.Name, fset, files, nil)
+	if err != nil {
+		t.Fatalf("generated source doesn't type check: %v", err)
+	}
+}
+
+func parseDir(fset *token.FileSet, dir string) ([]*ast.File, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*ast.File, 0)
+	for _, name := range names {
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// testdataImporter import the packages of testdata from their dirs, others from source
+type testdataImporter struct {
+	fset     *token.FileSet
+	source   types.Importer
+	packages map[string]*types.Package
+}
+
+func newTestdataImporter(fset *token.FileSet) *testdataImporter {
+	return &testdataImporter{
+		fset:     fset,
+		source:   importer.ForCompiler(fset, "source", nil),
+		packages: make(map[string]*types.Package),
+	}
+}
+
+func (imp *testdataImporter) Import(path string) (*types.Package, error) {
+	dir, ok := testdataPackages[path]
+	if !ok {
+		return imp.source.Import(path)
+	}
+	if pkg, ok := imp.packages[path]; ok {
+		return pkg, nil
+	}
+	files, err := parseDir(imp.fset, dir)
+	if err != nil {
+		return nil, err
+	}
+	config := &types.Config{Importer: imp}
+	pkg, err := config.Check(path, imp.fset, files, nil)
+	if err != nil {
+		return nil, fmt.Errorf("type check %s failed: %v", path, err)
+	}
+	imp.packages[path] = pkg
+	return pkg, nil
+}
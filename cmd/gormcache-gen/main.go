@@ -0,0 +1,102 @@
+// gormcache-gen generates typed cached dao from annotated sql dao methods.
+//
+// Annotate the sql dao methods with the same syntax as 'notify' tag (func is the method itself):
+//
+//	// GetByName get user by name
+//	// gormcache:type=concrete;keys=['Name'];args=[0]
+//	func (dao *UserSQLDao) GetByName(name string) *UserDo
+//
+// the wrapper of 'aggregate' method returns the type of its first result:
+//
+//	// gormcache:type=aggregate;keys=['Status'];args=[0]
+//	func (dao *UserSQLDao) CountByStatus(status int) int64
+//
+// the 'range' method gets a '{Method}Page' wrapper too, which returns a page of the range with the total count.
+//
+// then put the directive in the package of sql dao:
+//
+//	//go:generate gormcache-gen -type UserSQLDao -do UserDo
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeName   = flag.String("type", "", "sql dao type name, required")
+	doName     = flag.String("do", "", "database object model type name, like 'UserDo' or 'model.UserDo', required")
+	cacheName  = flag.String("name", "", "generated cache dao type name, default is sql dao type name with 'SQLDao' replaced by 'CacheDao'")
+	sqlDaoExpr = flag.String("sqldao", "", "expression to get sql dao instance, default '&{type}{}'")
+	prefix     = flag.String("prefix", "", "object cache prefix of cache dao")
+	output     = flag.String("output", "", "output file name, default '{name}_gen.go' in snake case")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gormcache-gen -type SQLDaoType -do DoType [flags] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if *typeName == "" || *doName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	config := &Config{
+		TypeName:   *typeName,
+		DoName:     *doName,
+		CacheName:  *cacheName,
+		SQLDaoExpr: *sqlDaoExpr,
+		Prefix:     *prefix,
+	}
+	if config.CacheName == "" {
+		config.CacheName = strings.TrimSuffix(strings.TrimSuffix(config.TypeName, "SQLDao"), "Dao") + "CacheDao"
+	}
+	if config.SQLDaoExpr == "" {
+		config.SQLDaoExpr = "&" + config.TypeName + "{}"
+	}
+
+	src, err := Generate(dir, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gormcache-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		outputName = toSnakeCase(config.CacheName) + "_gen.go"
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, outputName), src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gormcache-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func toSnakeCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if r >= 'A' && r <= 'Z' {
+			// split before a upper letter, except the inside of abbreviation like 'SQL'
+			if i > 0 && (runes[i-1] < 'A' || runes[i-1] > 'Z' || (i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z')) {
+				sb.WriteByte('_')
+			}
+			r = r - 'A' + 'a'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
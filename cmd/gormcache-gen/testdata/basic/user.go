@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type UserDo struct {
+	Id       uint64 `gorm:"primaryKey"`
+	Name     string
+	Email    string
+	Status   int
+	TenantId uint64
+	OwnerId  uint64
+	TeamId   uint64
+}
+
+type UserSQLDao struct {
+	db *gorm.DB
+}
+
+func (d *UserSQLDao) GetReadDbSource() *gorm.DB {
+	return d.db
+}
+
+// GetByName get user by name
+// gormcache:type=concrete;keys=['Name'];args=[0]
+func (d *UserSQLDao) GetByName(name string) *UserDo {
+	return nil
+}
+
+// GetByNames get users by names
+// gormcache:type=list;keys=['Name'];args=[0]
+func (d *UserSQLDao) GetByNames(names []string) ([]UserDo, error) {
+	return nil, nil
+}
+
+// GetByStatus get users of status
+// gormcache:type=range;keys=['Status'];args=[1]
+func (d *UserSQLDao) GetByStatus(db *gorm.DB, status int) []UserDo {
+	return nil
+}
+
+// CountByStatus count users of status
+// gormcache:type=aggregate;keys=['Status'];args=[0]
+func (d *UserSQLDao) CountByStatus(status int) (int64, error) {
+	return 0, nil
+}
+
+// GetAllByStatus get users of tenant and status
+// gormcache:type=multi;keys=['TenantId','Status'];args=[0,1]
+func (d *UserSQLDao) GetAllByStatus(tenantId uint64, status int) []UserDo {
+	return nil
+}
+
+// GetByOwner parameters named like the wrapper receiver and context are renamed
+// gormcache:type=concrete;keys=['OwnerId'];args=[1]
+func (d *UserSQLDao) GetByOwner(ctx context.Context, dao uint64) *UserDo {
+	return nil
+}
+
+// GetByTeam parameters named like the page arguments are renamed
+// gormcache:type=range;keys=['TeamId'];args=[1]
+func (d *UserSQLDao) GetByTeam(db *gorm.DB, offset uint64, limit int) []UserDo {
+	return nil
+}
+
+// Create not cached
+func (d *UserSQLDao) Create(user *UserDo) error {
+	return nil
+}
@@ -0,0 +1,9 @@
+package model
+
+import "gorm.io/gorm"
+
+type UserDo struct {
+	gorm.Model
+	Name   string
+	Status int
+}
@@ -0,0 +1,32 @@
+package dao
+
+import (
+	"context"
+
+	"example.com/app/model"
+	"gorm.io/gorm"
+)
+
+type UserDao struct {
+	db *gorm.DB
+}
+
+func NewUserDao(db *gorm.DB) *UserDao {
+	return &UserDao{db: db}
+}
+
+func (d UserDao) GetReadDbSource() *gorm.DB {
+	return d.db
+}
+
+// GetByName get user by name
+// gormcache:type=concrete;keys=['Name'];args=[1]
+func (d UserDao) GetByName(ctx context.Context, name string) (*model.UserDo, error) {
+	return nil, nil
+}
+
+// GetByStatus get users of status
+// gormcache:type=range;keys=['Status'];args=[1]
+func (d *UserDao) GetByStatus(db *gorm.DB, status int) ([]model.UserDo, error) {
+	return nil, nil
+}
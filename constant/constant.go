@@ -7,9 +7,11 @@ const (
 
 // notify type constant
 const (
-	NotifyTypeConcrete = "concrete"
-	NotifyTypeList     = "list"
-	NotifyTypeRange    = "range"
+	NotifyTypeConcrete  = "concrete"
+	NotifyTypeList      = "list"
+	NotifyTypeRange     = "range"
+	NotifyTypeAggregate = "aggregate"
+	NotifyTypeMulti     = "multi" // concrete key of non-unique index, matches multiple rows
 )
 
 // notify tag fields
@@ -19,3 +21,17 @@ const (
 	NotifyTagKeys = "keys"
 	NotifyTagArgs = "args"
 )
+
+// local cache evict policy constant
+const (
+	LocalCachePolicyLRU = "lru"
+	LocalCachePolicyLFU = "lfu"
+	LocalCachePolicyARC = "arc"
+)
+
+// compression algorithm constant
+const (
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+	CompressionGzip   = "gzip"
+)
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+)
+
+// GetByAggregate cache the scalar result (like COUNT, SUM, MAX) of sql dao method, return the first return value of method.
+// the result is cached under the version key of notify keys as 'concrete' does, so it's invalidated by 'NotifyModified'.
+func (base *CacheDaoBase) GetByAggregate(args ...interface{}) (interface{}, error) {
+	return base.getByAggregate(context.Background(), util.GetLastExecuteFuncName(), args...)
+}
+
+// GetByAggregateContext cache the scalar result of sql dao method, with context
+func (base *CacheDaoBase) GetByAggregateContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return base.getByAggregate(ctx, util.GetLastExecuteFuncName(), args...)
+}
+
+func (base *CacheDaoBase) getByAggregate(ctx context.Context, sqlMethodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetByAggregate", attrMethod.String(sqlMethodName))
+	defer span.end(&err)
+	span.keys(1)
+	stats := base.stats(sqlMethodName).traced(span)
+	defer stats.since(time.Now())
+
+	// try to get from cache first.
+	cacheKey, err := base.GetKeyContext(ctx, sqlMethodName, args...)
+	if err != nil || cacheKey == "" {
+		base.logMiss("GetByAggregate missed version", err, log.Method(sqlMethodName), log.Args(args))
+		stats.versionMiss(1)
+		return base.loadAggregate(ctx, sqlMethodName, args...)
+	}
+
+	cacheItem, err := base.getMethodCache(ctx, sqlMethodName, cacheKey)
+	if err != nil {
+		base.logMiss("GetByAggregate missed cache", err, log.Method(sqlMethodName), log.Args(args))
+		stats.miss(1)
+		return base.loadAggregate(ctx, sqlMethodName, args...)
+	}
+
+	base.logger().Debug("GetByAggregate hit cache", log.Method(sqlMethodName), log.Key(cacheKey))
+	retType, err := base.aggregateType(sqlMethodName)
+	if err != nil {
+		return nil, err
+	}
+	retPtr := reflect.New(retType)
+	err = base.Serializer.Deserialize(cacheItem.Value, retPtr.Interface())
+	if err != nil {
+		base.logger().Error("GetByAggregate deserialize failed", log.Method(sqlMethodName), log.Key(cacheKey), log.Err(err))
+		stats.deserializeFailure(1)
+		return base.loadAggregate(ctx, sqlMethodName, args...)
+	}
+	stats.hit(1)
+	return retPtr.Elem().Interface(), nil
+}
+
+// loadAggregate invoke sql dao method and cache the result
+func (base *CacheDaoBase) loadAggregate(ctx context.Context, sqlMethodName string, args ...interface{}) (interface{}, error) {
+	ret, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+	if err != nil {
+		return nil, err
+	}
+	err = base.SetAggregateCacheContext(ctx, ret, sqlMethodName, args...)
+	if err != nil {
+		base.logger().Error("GetByAggregate set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+	}
+	return ret, nil
+}
+
+// SetAggregateCache set cache for aggregate query
+func (base *CacheDaoBase) SetAggregateCache(value interface{}, methodName string, args ...interface{}) error {
+	return base.SetAggregateCacheContext(context.Background(), value, methodName, args...)
+}
+
+// SetAggregateCacheContext set cache for aggregate query, with context
+func (base *CacheDaoBase) SetAggregateCacheContext(ctx context.Context, value interface{}, methodName string, args ...interface{}) error {
+	data, err := base.Serializer.Serialize(value)
+	if err != nil {
+		return err
+	}
+	return base.setMethodCache(ctx, base.MakeKeyPrefix(methodName, args...), data, base.ExpireTime, methodName, args...)
+}
+
+// aggregateType the type of the first return value of sql dao method
+func (base *CacheDaoBase) aggregateType(methodName string) (reflect.Type, error) {
+	method := reflect.ValueOf(base.SQLDao).MethodByName(methodName)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("sql dao has no method '%s'", methodName)
+	}
+	if method.Type().NumOut() == 0 {
+		return nil, fmt.Errorf("sql dao method '%s' has no return value", methodName)
+	}
+	return method.Type().Out(0), nil
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"gorm.io/gorm"
+)
+
+func (d *testUserSQLDao) CountByStatus(status int) (int64, error) {
+	var count int64
+	err := d.db.Model(&testUser{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+// countQueries count the queries executed by db
+func countQueries(t *testing.T, db *gorm.DB) *int {
+	queries := 0
+	err := db.Callback().Query().Register("test:count_queries", func(db *gorm.DB) { queries++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queries
+}
+
+func TestAggregate(t *testing.T) {
+	for _, serializer := range []Serializer{&JSONSerializer{}, &MsgpackSerializer{}} {
+		db := newTestDB(t)
+		var count *CachedMethod
+		dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+			dao.Serializer = serializer
+			count = dao.MustBindMethod(&MethodBinding{
+				Method: (*testUserSQLDao).CountByStatus,
+				Type:   constant.NotifyTypeAggregate,
+				Keys:   []string{"Status"},
+				Args:   []int{0},
+			})
+		})
+		alice, _ := createTestUsers(t, db)
+		queries := countQueries(t, db)
+		check := func(status int, want int64, wantQueries int) {
+			t.Helper()
+			ret, err := count.Get(status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, ok := ret.(int64); !ok || n != want || *queries != wantQueries {
+				t.Fatalf("count of status %d by %T: got %#v with %d queries, want %d with %d queries", status, serializer, ret, *queries, want, wantQueries)
+			}
+		}
+
+		// loaded, then cached
+		check(1, 2, 1)
+		check(1, 2, 1)
+		check(2, 0, 2)
+		check(2, 0, 2)
+
+		// stale until notified
+		carol := &testUser{Name: "carol", Status: 1}
+		if err := db.Create(carol).Error; err != nil {
+			t.Fatal(err)
+		}
+		check(1, 2, 2)
+		if err := dao.NotifyModified(carol); err != nil {
+			t.Fatal(err)
+		}
+		check(1, 3, 3)
+		check(2, 0, 3)
+
+		// both the old and new status are invalidated by update
+		before := *alice
+		alice.Status = 2
+		if err := db.Save(alice).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := dao.NotifyUpdated(&before, alice); err != nil {
+			t.Fatal(err)
+		}
+		check(1, 2, 4)
+		check(2, 1, 5)
+	}
+}
+
+func TestAggregateInvalidatedByPlugin(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Use(NewInvalidationPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	var count *CachedMethod
+	newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		count = dao.MustBindMethod(&MethodBinding{
+			Method: (*testUserSQLDao).CountByStatus,
+			Type:   constant.NotifyTypeAggregate,
+			Keys:   []string{"Status"},
+			Args:   []int{0},
+		})
+	})
+	for i, want := range []int64{0, 1, 2} {
+		if i > 0 {
+			if err := db.Create(&testUser{Name: "u", Status: 1}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		ret, err := count.Get(1)
+		if err != nil || ret.(int64) != want {
+			t.Fatalf("count after %d created: got %v %v, want %d", i, ret, err, want)
+		}
+	}
+}
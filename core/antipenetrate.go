@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -33,14 +34,28 @@ func AntiPenetrate(proxyedFunc interface{}, inputValuesPtr, retValuesPtr *[]inte
 	return AntiPenetrateWithCache(proxyedFunc, inputValuesPtr, retValuesPtr, timeoutMillis, 0)
 }
 
+// AntiPenetrateContext proxy, the waiting is aborted when ctx is done
+func AntiPenetrateContext(ctx context.Context, proxyedFunc interface{}, inputValuesPtr, retValuesPtr *[]interface{}, timeoutMillis int64) error {
+	return AntiPenetrateWithCacheContext(ctx, proxyedFunc, inputValuesPtr, retValuesPtr, timeoutMillis, 0)
+}
+
 // AntiPenetrateWithCache proxy with cache
 func AntiPenetrateWithCache(proxyedFunc interface{}, inputValuesPtr, retValuesPtr *[]interface{}, timeoutMillis int64, cacheMillis int64) error {
+	return AntiPenetrateWithCacheContext(context.Background(), proxyedFunc, inputValuesPtr, retValuesPtr, timeoutMillis, cacheMillis)
+}
+
+// AntiPenetrateWithCacheContext proxy with cache, the waiting is aborted when ctx is done
+func AntiPenetrateWithCacheContext(ctx context.Context, proxyedFunc interface{}, inputValuesPtr, retValuesPtr *[]interface{}, timeoutMillis int64, cacheMillis int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// calculate map key based on `proxyedFunc` and `inputValues`
 	key, err := MakePenetrateKey(proxyedFunc, inputValuesPtr)
 	if err != nil {
 		return err
 	}
-	log.Logger.Debugf("Penetrate key: %s", key)
+	log.Default.Debug("penetrate", log.Key(key))
 
 	// check if cache has static cache
 	retValue, err := gc.Get(key)
@@ -68,15 +83,18 @@ func AntiPenetrateWithCache(proxyedFunc interface{}, inputValuesPtr, retValuesPt
 
 		select {
 		case <-wch:
-			log.Logger.Debug("Get result from main goroutine")
+			log.Default.Debug("get result from main goroutine", log.Key(key))
 		case <-time.After(time.Duration(timeoutMillis) * time.Millisecond):
-			log.Logger.Debug("Time out for waitting main goroutine")
+			log.Default.Debug("time out for waiting main goroutine", log.Key(key))
+		case <-ctx.Done():
+			log.Default.Debug("context done for waiting main goroutine", log.Key(key))
+			return ctx.Err()
 		}
 
 		*retValuesPtr = *wgInter.(*WrappedValue).Value
 	} else {
 		// if map doesn't have value, this goroutine should penetrate this method to find value
-		log.Logger.Debug("Penetrate into method")
+		log.Default.Debug("penetrate into method", log.Key(key))
 		defer wrappedValue.WaitGroup.Done()
 		_, funcValue := util.GetRealTypeAndValue(proxyedFunc)
 
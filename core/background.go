@@ -0,0 +1,21 @@
+package core
+
+import "context"
+
+// backgroundKey marks the context derived from context.Background() by cache dao itself (like the span context),
+// so it's still taken as no context given by caller.
+type backgroundKey struct{}
+
+// isBackground check if ctx is context.Background(), or derived from it by 'keepBackground'.
+// the *gorm.DB args of sql dao methods keep their own context if no context is given by caller.
+func isBackground(ctx context.Context) bool {
+	return ctx == context.Background() || ctx.Value(backgroundKey{}) != nil
+}
+
+// keepBackground mark ctx derived from parent as background if parent is
+func keepBackground(parent, ctx context.Context) context.Context {
+	if ctx == parent || !isBackground(parent) || ctx.Value(backgroundKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type testCtxKey struct{}
+
+func TestBackground(t *testing.T) {
+	background := context.Background()
+	given := context.WithValue(background, testCtxKey{}, "caller")
+	derived := keepBackground(background, context.WithValue(background, testCtxKey{}, "span"))
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"background", background, true},
+		{"todo", context.TODO(), false},
+		{"given by caller", given, false},
+		{"derived from background", derived, true},
+		{"derived from derived", keepBackground(derived, context.WithValue(derived, testCtxKey{}, "child")), true},
+		{"values of derived", context.WithValue(derived, testCtxKey{}, "child"), true},
+		{"derived from given", keepBackground(given, context.WithValue(given, testCtxKey{}, "span")), false},
+	}
+	for _, c := range cases {
+		if got := isBackground(c.ctx); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if keepBackground(background, background) != background {
+		t.Error("background should be kept as it is")
+	}
+}
+
+// TestSQLDaoKeepsDBContext the *gorm.DB args of sql dao method keep their own context when no context is given,
+// even if the span context is derived from background.
+func TestSQLDaoKeepsDBContext(t *testing.T) {
+	dao, db, _ := newTracedTestCacheDao(t)
+	var got interface{}
+	err := db.Callback().Query().Before("gorm:query").Register("test:record_ctx", func(db *gorm.DB) {
+		got = db.Statement.Context.Value(testCtxKey{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	callerDB := db.WithContext(context.WithValue(context.Background(), testCtxKey{}, "db"))
+
+	for _, c := range []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"without context", context.Background(), "db"},
+		{"with context", context.WithValue(context.Background(), testCtxKey{}, "caller"), "caller"},
+	} {
+		ctx, span := dao.startSpan(c.ctx, "Test")
+		if span == nil {
+			t.Fatal("span should be started")
+		}
+		_, err := dao.invokeSQLDao(ctx, "GetByStatus", callerDB, 1)
+		span.end(&err)
+		if err != nil || got != c.want {
+			t.Fatalf("%s: got context of %v %v, want %s", c.name, got, err, c.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,12 +9,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/zhyeah/gorm-cache/constant"
 	"github.com/zhyeah/gorm-cache/log"
 	"github.com/zhyeah/gorm-cache/tag"
 	"github.com/zhyeah/gorm-cache/util"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // NotifyInfo Cache key update information
@@ -30,15 +32,40 @@ type CacheDaoBase struct {
 	SQLDao       interface{} // sql dao
 	ReadDBSource *gorm.DB    // get from SQLDao for specified 'GetById' and 'GetByIds'
 
-	ExpireTime int // default
+	ExpireTime         int // default
+	NegativeExpireTime int // expire seconds of the cache for absent rows and empty results, disabled if 0
+	RangePageMaxIds    int // max primary keys cached for each range by 'GetByRangePage', default 1000
 
-	IDFieldName         string
+	IDFieldName         string // name of the first primary field
 	ObjectCachePrefix   string
 	VersionPrefix       string                 // version prefix for cache key prefix
 	NotifyInfos         []*NotifyInfo          // when modify happended, upgrade the cache version tagged by this list
 	MethodNotifyInfoMap map[string]*NotifyInfo // 'NotifyInfo' recorded by method name
 
-	Serializer Serializer // which serializer use for cache
+	Serializer     Serializer           // which serializer use for cache
+	Store          CacheStore           // which cache store use, 'DefaultStore' if nil
+	Logger         log.Interface        // which logger use, 'log.Default' if nil, set it before 'Initialize'
+	Metrics        Metrics              // which metrics hook use, 'DefaultMetrics' if nil
+	TracerProvider trace.TracerProvider // which tracer provider use for spans, 'DefaultTracerProvider' if nil
+	daoLogger      log.Interface        // 'Logger' with dao name, built at 'Initialize'
+
+	LocalCache *LocalCacheConfig // in-process object cache tier in front of 'Store', disabled if nil
+	localCache *localCache
+
+	InvalidationBus InvalidationBus                // broadcast invalidations to other processes, 'DefaultInvalidationBus' if nil
+	OnInvalidation  func(msg *InvalidationMessage) // invoked when invalidation from other process received
+	instanceID      string
+	subscribedBus   InvalidationBus // bus subscribed at 'Initialize', unsubscribed by 'Close'
+
+	DelayedNotifyMillis int64 // if > 0, notify again after the delay to clear stale data set by concurrent readers
+
+	SchemaEnvelope    bool   // wrap object caches with the schema fingerprint of Do, the ones of other schema are taken as missed
+	schemaFingerprint []byte // fingerprint of Do fields, resolved at 'Initialize'
+
+	boundMethods []*CachedMethod // methods declared by 'BindMethod'
+
+	schema        *schema.Schema  // parsed from Do, for table name and columns
+	primaryFields []*schema.Field // primary key fields of Do
 }
 
 // Initialize 初始化信息
@@ -52,6 +79,13 @@ func (base *CacheDaoBase) Initialize(instance interface{}) error {
 	if base.Serializer == nil {
 		base.Serializer = &JSONSerializer{}
 	}
+	if base.RangePageMaxIds <= 0 {
+		base.RangePageMaxIds = defaultRangePageMaxIds
+	}
+
+	if base.LocalCache != nil {
+		base.localCache = newLocalCache(base.LocalCache)
+	}
 
 	base.NotifyInfos = make([]*NotifyInfo, 0)
 	base.MethodNotifyInfoMap = make(map[string]*NotifyInfo)
@@ -63,22 +97,24 @@ func (base *CacheDaoBase) Initialize(instance interface{}) error {
 	} else {
 		base.ObjectCachePrefix += "_" + doType.Name()
 	}
+	base.schemaFingerprint = schemaFingerprint(doType)
+	base.daoLogger = base.newLogger()
 
-	id := util.GetSpecifiedFieldValue(base.Do, "Id")
-	if id != nil {
-		base.IDFieldName = "Id"
-	} else {
-		id = util.GetSpecifiedFieldValue(base.Do, "ID")
-		if id != nil {
-			base.IDFieldName = "ID"
-		} else {
-			return errors.New("do object should contains 'Id' or 'ID' field that mapped to primary key")
-		}
+	// get sql dao read gorm
+	rets := util.ReflectInvokeMethod(base.SQLDao, "GetReadDbSource")
+	if len(rets) == 0 {
+		return errors.New("your sql dao should have method 'GetReadDbSource', which means you need extend 'BaseDao'")
+	}
+	base.ReadDBSource = rets[0].(*gorm.DB)
+
+	// table name and primary key columns are resolved by the naming strategy of read gorm
+	err := base.resolveSchema()
+	if err != nil {
+		return err
 	}
 
 	// initialize notify infos
 	instanceType := util.GetPointToType(reflect.TypeOf(instance))
-	filterMap := make(map[string]int)
 	for i := 0; i < instanceType.NumField(); i++ {
 		if !strings.HasPrefix(instanceType.Field(i).Name, "meta") {
 			continue
@@ -88,183 +124,370 @@ func (base *CacheDaoBase) Initialize(instance interface{}) error {
 		if err != nil {
 			return err
 		}
+		base.registerNotifyInfo(notify.Func, notify.Type, notify.Keys, notify.Args)
+	}
 
-		// FIXME: if sort `notify.Keys`, the duplicated notifyInfo can be more less.
-		versionKeyPrefix := "V_" + doType.Name() + "_" + strings.Join(notify.Keys, "_")
-		notifyInfo := NotifyInfo{
-			Type:             notify.Type,
-			Fields:           notify.Keys,
-			Args:             notify.Args,
-			VersionKeyPrefix: versionKeyPrefix,
-		}
+	// notify infos declared by 'BindMethod'
+	for _, method := range base.boundMethods {
+		base.registerNotifyInfo(method.Name, method.Type, method.Keys, method.Args)
+	}
 
-		// make notiyInfo array
-		if _, ok := filterMap[versionKeyPrefix]; !ok {
-			filterMap[versionKeyPrefix] = 1
-			base.NotifyInfos = append(base.NotifyInfos, &notifyInfo)
-		}
+	// typos in notify declarations fail here rather than panic at request time
+	err = base.validateNotifyInfos()
+	if err != nil {
+		return err
+	}
 
-		// make method notify map
-		base.MethodNotifyInfoMap[notify.Func] = &notifyInfo
+	// sql of range is captured to count for paged range cache
+	err = registerRangeSQLCapture(base.ReadDBSource)
+	if err != nil {
+		return err
 	}
 
-	// get sql dao read gorm
-	rets := util.ReflectInvokeMethod(base.SQLDao, "GetReadDbSource")
-	if len(rets) == 0 {
-		return errors.New("your sql dao should have method 'GetReadDbSource', which means you need extend 'BaseDao'")
+	// for finding daos by model type in gorm callbacks
+	registerCacheDao(base)
+
+	// subscribe invalidations from other processes for local copies
+	base.instanceID = newInstanceID()
+	if bus := base.invalidationBus(); bus != nil && (base.localCache != nil || base.OnInvalidation != nil) {
+		err := subscribeInvalidation(bus, base)
+		if err != nil {
+			return err
+		}
+		base.subscribedBus = bus
 	}
-	base.ReadDBSource = rets[0].(*gorm.DB)
 
 	return nil
 }
 
+// Close unregister the dao from the invalidation plugin and unsubscribe its invalidation bus,
+// invoke it when the dao is dropped before the process exits. the bus isn't closed as it may be shared.
+func (base *CacheDaoBase) Close() error {
+	unregisterCacheDao(base)
+	if base.subscribedBus != nil {
+		unsubscribeInvalidation(base.subscribedBus, base)
+		base.subscribedBus = nil
+	}
+	return nil
+}
+
 // GetById try get from cache first, if absent, load it from sql
 func (base *CacheDaoBase) GetById(id uint64) (interface{}, error) {
+	return base.GetByIdContext(context.Background(), id)
+}
+
+// GetByIdContext try get from cache first, if absent, load it from sql, with context
+func (base *CacheDaoBase) GetByIdContext(ctx context.Context, id uint64) (interface{}, error) {
 	if id <= 0 {
 		return nil, errors.New("illegal id, should >= 0")
 	}
+	return base.getByPrimaryKey(ctx, id)
+}
+
+// GetByPrimaryKey like 'GetById', for any type of primary key, use CompositeKey for composite primary key
+func (base *CacheDaoBase) GetByPrimaryKey(key interface{}) (interface{}, error) {
+	return base.GetByPrimaryKeyContext(context.Background(), key)
+}
+
+// GetByPrimaryKeyContext like 'GetById', for any type of primary key, with context
+func (base *CacheDaoBase) GetByPrimaryKeyContext(ctx context.Context, key interface{}) (interface{}, error) {
+	key, err := base.normalizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return base.getByPrimaryKey(ctx, key)
+}
+
+func (base *CacheDaoBase) getByPrimaryKey(ctx context.Context, key interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetById")
+	defer span.end(&err)
+	span.keys(1)
+	stats := base.stats(metricsMethodGetById).traced(span)
+	defer stats.since(time.Now())
+
+	// try local cache tier first
+	if objInstancePtr, ok := base.getLocalObject(key); ok {
+		base.logger().Debug("hit local cache", log.Key(key))
+		stats.hit(1)
+		return objInstancePtr, nil
+	}
 
 	// firstly, get object cache key
-	objCacheKey, err := base.GetObjectKey(id)
+	objCacheKey, err := base.getObjectKey(ctx, key)
 	if err != nil || objCacheKey == "" {
-		log.Logger.Warnf("missed object key for id %d, err: %v", id, err)
-		return base.SetObjectCacheForGetById(id)
+		base.logMiss("missed object key", err, log.Key(key))
+		stats.versionMiss(1)
+		span.absent([]interface{}{key})
+		return base.setObjectCacheForKey(ctx, key)
 	}
 
 	// get object cache
-	objCacheItem, err := MemcacheClient.Get(objCacheKey)
+	objCacheItem, err := base.getObjectCache(ctx, objCacheKey)
 	if err != nil {
-		log.Logger.Warnf("2. missed object cache for id %d, err: %v", id, err)
-		return base.SetObjectCacheForGetById(id)
+		base.logMiss("missed object cache", err, log.Key(key))
+		stats.miss(1)
+		span.absent([]interface{}{key})
+		return base.setObjectCacheForKey(ctx, key)
+	}
+
+	if isNegativeValue(objCacheItem.Value) {
+		base.logger().Debug("hit negative cache", log.Key(key))
+		stats.hit(1)
+		return nil, nil
 	}
 
 	objInstancePtr := base.makeObjInstancePtr()
-	err = base.Serializer.Deserialize(objCacheItem.Value, objInstancePtr)
+	err = base.deserializeObject(objCacheItem.Value, objInstancePtr)
+	if err == ErrSchemaMismatch {
+		// written for another schema of Do, repopulate it
+		base.logger().Debug("missed object cache of current schema", log.Key(key))
+		stats.miss(1)
+		span.absent([]interface{}{key})
+		return base.setObjectCacheForKey(ctx, key)
+	}
 	if err != nil {
 		// some serialize error, throw it out!
+		stats.deserializeFailure(1)
 		return nil, err
 	}
-	log.Logger.Debugf("hit cache for id %d", id)
+	base.setLocalObject(key, objCacheItem.Value)
+	base.logger().Debug("hit object cache", log.Key(key))
+	stats.hit(1)
 	return objInstancePtr, nil
 }
 
 // GetByIds try to get from cache first, if absent, load them from sql
 func (base *CacheDaoBase) GetByIds(ids []uint64) (interface{}, error) {
-	if len(ids) <= 0 {
+	return base.GetByIdsContext(context.Background(), ids)
+}
+
+// GetByIdsContext try to get from cache first, if absent, load them from sql, with context
+func (base *CacheDaoBase) GetByIdsContext(ctx context.Context, ids []uint64) (interface{}, error) {
+	return base.getByPrimaryKeys(ctx, idsToKeys(ids))
+}
+
+// GetByPrimaryKeys like 'GetByIds', keys is slice of any type of primary key, use []CompositeKey for composite primary key
+func (base *CacheDaoBase) GetByPrimaryKeys(keys interface{}) (interface{}, error) {
+	return base.GetByPrimaryKeysContext(context.Background(), keys)
+}
+
+// GetByPrimaryKeysContext like 'GetByIds', keys is slice of any type of primary key, with context
+func (base *CacheDaoBase) GetByPrimaryKeysContext(ctx context.Context, keys interface{}) (interface{}, error) {
+	if util.IsNil(keys) {
 		return base.makeObjListPtr(), nil
 	}
+	if !util.RealTypeCheck(keys, reflect.Slice) {
+		return nil, errors.New("keys should be slice")
+	}
+	keyList := make([]interface{}, 0, util.GetListLength(keys))
+	for i := 0; i < util.GetListLength(keys); i++ {
+		key, err := base.normalizeKey(util.GetListElement(keys, i))
+		if err != nil {
+			return nil, err
+		}
+		keyList = append(keyList, key)
+	}
+	return base.getByPrimaryKeys(ctx, keyList)
+}
+
+func (base *CacheDaoBase) getByPrimaryKeys(ctx context.Context, keys []interface{}) (ret interface{}, err error) {
+	if len(keys) <= 0 {
+		return base.makeObjListPtr(), nil
+	}
+	ctx, span := base.startSpan(ctx, "GetByIds")
+	defer span.end(&err)
+	span.keys(len(keys))
+	stats := base.stats(metricsMethodGetByIds).traced(span)
+	defer stats.since(time.Now())
+
+	retList := base.makeObjListPtr()
+	listVal := reflect.ValueOf(retList).Elem()
+
+	// try local cache tier first
+	remoteKeys := keys
+	if base.localCache != nil {
+		remoteKeys = make([]interface{}, 0)
+		for i := range keys {
+			if objInstancePtr, ok := base.getLocalObject(keys[i]); ok {
+				listVal.Set(reflect.Append(listVal, reflect.ValueOf(objInstancePtr).Elem()))
+			} else {
+				remoteKeys = append(remoteKeys, keys[i])
+			}
+		}
+		stats.hit(len(keys) - len(remoteKeys))
+		if len(remoteKeys) == 0 {
+			return base.reorderByKeys(keys, retList), nil
+		}
+	}
 
-	absentIds := make([]uint64, 0)
+	absentKeys := make([]interface{}, 0)
 
 	// get obj list cache versions
-	startTime := time.Now().UnixNano() / 1e6
-	objCacheKeys, err := base.GetObjectKeys(ids)
-	log.Logger.Warnf("get ids while get by keys cost time: %d", time.Now().UnixNano()/1e6-startTime)
+	startTime := time.Now()
+	objCacheKeys, err := base.getObjectKeys(ctx, remoteKeys)
+	base.logger().Debug("get object versions", log.F("count", len(remoteKeys)), log.Latency(startTime))
 	if err != nil {
 		// return from sql with cache set
-		log.Logger.Warnf("missed object cache keys for ids %v, err: %v", ids, err)
-		return base.SetObjectCachesForGetByIds(ids)
+		base.logMiss("missed object versions", err, log.Key(keys))
+		stats.versionMiss(len(remoteKeys))
+		span.absent(keys)
+		return base.setObjectCachesForKeys(ctx, keys)
 	}
 
-	keys := make([]string, 0)
-	retIds := make([]uint64, 0)
-	for i := range ids {
-		if v, ok := objCacheKeys[ids[i]]; !ok {
-			absentIds = append(absentIds, ids[i])
+	cacheKeys := make([]string, 0)
+	cacheKeyMap := make(map[string]interface{}) // object cache key -> primary key
+	for i := range remoteKeys {
+		if v, ok := objCacheKeys[base.encodeKey(remoteKeys[i])]; !ok {
+			absentKeys = append(absentKeys, remoteKeys[i])
 		} else {
-			keys = append(keys, v)
-			retIds = append(retIds, ids[i])
+			cacheKeys = append(cacheKeys, v)
+			cacheKeyMap[v] = remoteKeys[i]
 		}
 	}
+	stats.versionMiss(len(absentKeys))
 
 	// getMulti from cache
-	startTime = time.Now().UnixNano() / 1e6
-	objCacheItems, err := MemcacheClient.GetMulti(keys)
-	log.Logger.Warnf("get ids while gets cost time: %d", time.Now().UnixNano()/1e6-startTime)
+	startTime = time.Now()
+	objCacheItems, err := base.getObjectCaches(ctx, cacheKeys)
+	base.logger().Debug("get object caches", log.F("count", len(cacheKeys)), log.Latency(startTime))
 	if err != nil {
-		log.Logger.Warnf("missed object caches for ids %d, err: %v", ids, err)
-		return base.SetObjectCachesForGetByIds(ids)
-	}
-
-	retList := base.makeObjListPtr()
-	listVal := reflect.ValueOf(retList).Elem()
-	cacheIdMap := make(map[uint64]int)
-	for k, v := range objCacheItems {
-		cacheIdMap[base.ResolveIdFromObjectCacheKey(k)] = 1
+		base.logMiss("missed object caches", err, log.Key(keys))
+		stats.miss(len(cacheKeys))
+		span.absent(keys)
+		return base.setObjectCachesForKeys(ctx, keys)
+	}
+
+	for _, cacheKey := range cacheKeys {
+		key := cacheKeyMap[cacheKey]
+		v, ok := objCacheItems[cacheKey]
+		if !ok {
+			absentKeys = append(absentKeys, key)
+			stats.miss(1)
+			continue
+		}
+		if isNegativeValue(v.Value) {
+			// known absent
+			stats.hit(1)
+			continue
+		}
 		objInstancePtr := base.makeObjInstancePtr()
-		err = base.Serializer.Deserialize(v.Value, objInstancePtr)
+		err = base.deserializeObject(v.Value, objInstancePtr)
+		if err == ErrSchemaMismatch {
+			// written for another schema of Do, repopulate it
+			absentKeys = append(absentKeys, key)
+			stats.miss(1)
+			continue
+		}
 		if err != nil {
+			base.logger().Warn("deserialize object cache failed", log.Key(key), log.Err(err))
+			absentKeys = append(absentKeys, key)
+			stats.deserializeFailure(1)
 			continue
 		}
+		stats.hit(1)
+		base.setLocalObject(key, v.Value)
 		listVal.Set(reflect.Append(listVal, reflect.ValueOf(objInstancePtr).Elem()))
 	}
 
-	for i := range retIds {
-		if _, ok := cacheIdMap[retIds[i]]; !ok {
-			absentIds = append(absentIds, retIds[i])
-		}
-	}
+	base.logger().Debug("absent object caches", log.Key(absentKeys))
+	span.absent(absentKeys)
 
-	log.Logger.Debugf("absent ids: %v", absentIds)
-
-	if len(absentIds) > 0 {
-		// try get from sql for absent ids
-		absentList, err := base.SetObjectCachesForGetByIds(absentIds)
+	if len(absentKeys) > 0 {
+		// try get from sql for absent keys
+		absentList, err := base.setObjectCachesForKeys(ctx, absentKeys)
 		if err != nil {
-			log.Logger.Warnf("missed object caches for absentIds %d, err: %v", absentIds, err)
-			return base.SetObjectCachesForGetByIds(ids)
+			base.logger().Warn("load absent objects failed", log.Key(absentKeys), log.Err(err))
+			return base.setObjectCachesForKeys(ctx, keys)
 		}
 
 		// append absent list to retList
 		absentListValue := reflect.ValueOf(absentList).Elem()
-		log.Logger.Debugf("absent list vals: %v", absentListValue)
 		for i := 0; i < absentListValue.Len(); i++ {
 			listVal.Set(reflect.Append(listVal, absentListValue.Index(i)))
 		}
 	}
 
-	return base.reorderByIds(ids, retList), nil
+	return base.reorderByKeys(keys, retList), nil
 }
 
 // GetByConcreteKey get single object by concrete key
 func (base *CacheDaoBase) GetByConcreteKey(args ...interface{}) (interface{}, error) {
-	sqlMethodName := util.GetLastExecuteFuncName()
+	return base.getByConcreteKey(context.Background(), util.GetLastExecuteFuncName(), args...)
+}
+
+// GetByConcreteKeyContext get single object by concrete key, with context
+func (base *CacheDaoBase) GetByConcreteKeyContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return base.getByConcreteKey(ctx, util.GetLastExecuteFuncName(), args...)
+}
+
+func (base *CacheDaoBase) getByConcreteKey(ctx context.Context, sqlMethodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetByConcreteKey", attrMethod.String(sqlMethodName))
+	defer span.end(&err)
+	span.keys(1)
+	stats := base.stats(sqlMethodName).traced(span)
+	defer stats.since(time.Now())
 
 	// try to get from cache first.
-	cacheKey, err := base.GetKey(sqlMethodName, args...)
+	cacheKey, err := base.GetKeyContext(ctx, sqlMethodName, args...)
 	if err != nil || cacheKey == "" {
 		// get obj return value from sql dao
-		log.Logger.Errorf("GetByConcreteKey missed for args: %v, err: %v", args, err)
-		retVals := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, args...)
-		obj := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
-		err := base.SetCache(obj, sqlMethodName, args...)
+		base.logMiss("GetByConcreteKey missed version", err, log.Method(sqlMethodName), log.Args(args))
+		stats.versionMiss(1)
+		obj, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
+		err = base.SetCacheContext(ctx, obj, sqlMethodName, args...)
 		if err != nil {
-			log.Logger.Errorf("GetByConcreteKey set cache failed for args: %v, err: %v", args, err)
+			base.logger().Error("GetByConcreteKey set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
 		}
 		return obj, nil
 	}
 
 	// try to get from cache
-	cacheItem, err := MemcacheClient.Get(cacheKey)
+	cacheItem, err := base.getMethodCache(ctx, sqlMethodName, cacheKey)
 	if err != nil {
-		log.Logger.Warnf("GetByConcreteKey missed for args %d, err: %v", args, err)
-		retVals := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, args...)
-		obj := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
-		err := base.SetCache(obj, sqlMethodName, args...)
+		base.logMiss("GetByConcreteKey missed cache", err, log.Method(sqlMethodName), log.Args(args))
+		stats.miss(1)
+		obj, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
+		err = base.SetCacheContext(ctx, obj, sqlMethodName, args...)
 		if err != nil {
-			log.Logger.Errorf("GetByConcreteKey set cache failed for args: %v, err: %v", args, err)
+			base.logger().Error("GetByConcreteKey set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
 		}
 		return obj, nil
 	}
 
-	log.Logger.Debugf("hit concrete key cache.")
-	idVal := util.ConvertStringToUNumber(string(cacheItem.Value))
-	return base.GetById(idVal)
+	base.logger().Debug("GetByConcreteKey hit cache", log.Method(sqlMethodName), log.Key(cacheKey))
+	stats.hit(1)
+	if isNegativeValue(cacheItem.Value) {
+		return nil, nil
+	}
+	key, err := base.unmarshalKey(cacheItem.Value)
+	if err != nil {
+		stats.deserializeFailure(1)
+		return nil, err
+	}
+	return base.getByPrimaryKey(ctx, key)
 }
 
 // GetByConcreteKeys get objecgts by concrete keys
 func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, error) {
-	sqlMethodName := util.GetLastExecuteFuncName()
+	return base.getByConcreteKeys(context.Background(), util.GetLastExecuteFuncName(), args...)
+}
+
+// GetByConcreteKeysContext get objecgts by concrete keys, with context
+func (base *CacheDaoBase) GetByConcreteKeysContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return base.getByConcreteKeys(ctx, util.GetLastExecuteFuncName(), args...)
+}
+
+func (base *CacheDaoBase) getByConcreteKeys(ctx context.Context, sqlMethodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetByConcreteKeys", attrMethod.String(sqlMethodName))
+	defer span.end(&err)
+	stats := base.stats(sqlMethodName).traced(span)
+	defer stats.since(time.Now())
 
 	// find out the list args
 	listArgIndexs := make([]int, 0)
@@ -275,9 +498,9 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 			listArgIndexMap[i] = 1
 		}
 	}
-	log.Logger.Debugf("list args indexs: %v", listArgIndexs)
+	base.logger().Debug("GetByConcreteKeys list args", log.Method(sqlMethodName), log.F("indexes", listArgIndexs))
 	if len(listArgIndexs) == 0 {
-		log.Logger.Error("There is no list arg in args")
+		base.logger().Error("GetByConcreteKeys has no list arg", log.Method(sqlMethodName), log.Args(args))
 		return nil, errors.New("There is no list arg in args")
 	}
 	// check if the list sizes are equal
@@ -285,12 +508,11 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 	for i := range listArgIndexs {
 		currentLength := util.GetListLength(args[listArgIndexs[i]])
 		if lastLength != -1 && lastLength != currentLength {
-			log.Logger.Error("The length of list parameter is not equal")
+			base.logger().Error("GetByConcreteKeys list args have different length", log.Method(sqlMethodName), log.Args(args))
 			return nil, errors.New("the length of list parameter is not equal")
 		}
 		lastLength = currentLength
 	}
-	log.Logger.Debugf("list param length: %d", lastLength)
 	// split params into arrays
 	paramArrays := make([][]interface{}, lastLength)
 	for i := 0; i < lastLength; i++ {
@@ -304,23 +526,27 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		}
 		paramArrays[i] = currentParams
 	}
-	log.Logger.Debugf("paramArrays: %v", paramArrays)
+	span.keys(lastLength)
+	base.logger().Debug("GetByConcreteKeys split params", log.Method(sqlMethodName), log.F("params", paramArrays))
 
 	// make version keys
-	versionsMap, err := base.GetVersions(sqlMethodName, paramArrays)
+	versionsMap, err := base.GetVersionsContext(ctx, sqlMethodName, paramArrays)
 	if err != nil {
-		log.Logger.Errorf("GetByConcreteKeys get versions failed, args: %v err: %v", args, err)
-		retVals := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, args...)
-		objs := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
+		base.logger().Warn("GetByConcreteKeys get versions failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+		stats.versionMiss(lastLength)
+		objs, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
 		go func() {
-			err := base.SetCaches(objs, sqlMethodName, paramArrays)
+			err := base.SetCachesContext(context.Background(), objs, sqlMethodName, paramArrays)
 			if err != nil {
-				log.Logger.Errorf("GetByConcreteKeys set caches failed for args: %v, err: %v", args, err)
+				base.logger().Error("GetByConcreteKeys set caches failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
 			}
 		}()
 		objsType := reflect.TypeOf(objs)
 		objsValue := reflect.ValueOf(objs)
-		if objsType.Kind() == reflect.Slice {
+		if objsType != nil && objsType.Kind() == reflect.Slice {
 			retList := base.makeObjListPtr()
 			listVal := reflect.ValueOf(retList).Elem()
 			for i := 0; i < objsValue.Len(); i++ {
@@ -330,34 +556,41 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		}
 		return objs, nil
 	}
-	log.Logger.Debugf("versionsMap: %v", versionsMap)
+	base.logger().Debug("GetByConcreteKeys got versions", log.Method(sqlMethodName), log.F("versions", versionsMap))
+	notifyInfo := base.MethodNotifyInfoMap[sqlMethodName]
 	cacheKey := make([]string, 0)
+	cacheKeyParams := make(map[string][]interface{})
 	for i := range paramArrays {
 		akey := base.JoinArgs(sqlMethodName, paramArrays[i]...)
 		if versionStr, ok := versionsMap[akey]; ok {
 			// key prefix
 			keyPrefix := base.MakeKeyPrefix(sqlMethodName, paramArrays[i]...)
 			cacheKey = append(cacheKey, base.MakeKey(keyPrefix, versionStr))
+			cacheKeyParams[cacheKey[len(cacheKey)-1]] = paramArrays[i]
 		}
 	}
+	stats.versionMiss(lastLength - len(cacheKey))
 
 	// get caches
-	startTime := time.Now().UnixNano() / 1e6
-	cacheItems, err := MemcacheClient.GetMulti(cacheKey)
-	log.Logger.Warnf("get multi cost time: %d", time.Now().UnixNano()/1e6-startTime)
+	startTime := time.Now()
+	cacheItems, err := base.getMethodCaches(ctx, sqlMethodName, cacheKey)
+	base.logger().Debug("GetByConcreteKeys get caches", log.Method(sqlMethodName), log.F("count", len(cacheKey)), log.Latency(startTime))
 	if err != nil {
-		log.Logger.Errorf("GetByConcreteKeys get caches failed, args: %v err: %v", args, err)
-		retVals := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, args...)
-		objs := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
+		base.logger().Warn("GetByConcreteKeys get caches failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+		stats.miss(len(cacheKey))
+		objs, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
 		go func() {
-			err := base.SetCaches(objs, sqlMethodName, paramArrays)
+			err := base.SetCachesContext(context.Background(), objs, sqlMethodName, paramArrays)
 			if err != nil {
-				log.Logger.Errorf("GetByConcreteKeys set caches failed for args: %v, err: %v", args, err)
+				base.logger().Error("GetByConcreteKeys set caches failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
 			}
 		}()
 		objsType := reflect.TypeOf(objs)
 		objsValue := reflect.ValueOf(objs)
-		if objsType.Kind() == reflect.Slice {
+		if objsType != nil && objsType.Kind() == reflect.Slice {
 			retList := base.makeObjListPtr()
 			listVal := reflect.ValueOf(retList).Elem()
 			for i := 0; i < objsValue.Len(); i++ {
@@ -368,27 +601,43 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		return objs, nil
 	}
 
-	idArr := make([]uint64, 0)
-	for _, v := range cacheItems {
-		idArr = append(idArr, util.ConvertStringToUNumber(string(v.Value)))
+	stats.miss(len(cacheKey) - len(cacheItems))
+	keyArr := make([]interface{}, 0)
+	negativeKeys := make(map[string]int) // params known to match nothing
+	for k, v := range cacheItems {
+		if isNegativeValue(v.Value) {
+			negativeKeys[base.getParamMapKey(cacheKeyParams[k], notifyInfo)] = 1
+			stats.hit(1)
+			continue
+		}
+		key, err := base.unmarshalKey(v.Value)
+		if err != nil {
+			base.logger().Warn("GetByConcreteKeys unmarshal primary key failed", log.Method(sqlMethodName), log.Key(k), log.Err(err))
+			stats.deserializeFailure(1)
+			continue
+		}
+		stats.hit(1)
+		keyArr = append(keyArr, key)
 	}
-	log.Logger.Debugf("GetByConcreteKeys idArr: %v", idArr)
+	base.logger().Debug("GetByConcreteKeys hit caches", log.Method(sqlMethodName), log.Key(keyArr))
 
-	// get by ids
-	objs, err := base.GetByIds(idArr)
+	// get by keys
+	objs, err := base.getByPrimaryKeys(ctx, keyArr)
 	if err != nil {
-		log.Logger.Errorf("GetByConcreteKeys get caches failed, args: %v err: %v", args, err)
-		retVals := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, args...)
-		objs := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
+		base.logger().Warn("GetByConcreteKeys get caches failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+		objs, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
 		go func() {
-			err := base.SetCaches(objs, sqlMethodName, paramArrays)
+			err := base.SetCachesContext(context.Background(), objs, sqlMethodName, paramArrays)
 			if err != nil {
-				log.Logger.Errorf("GetByConcreteKeys set caches failed for args: %v, err: %v", args, err)
+				base.logger().Error("GetByConcreteKeys set caches failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
 			}
 		}()
 		objsType := reflect.TypeOf(objs)
 		objsValue := reflect.ValueOf(objs)
-		if objsType.Kind() == reflect.Slice {
+		if objsType != nil && objsType.Kind() == reflect.Slice {
 			retList := base.makeObjListPtr()
 			listVal := reflect.ValueOf(retList).Elem()
 			for i := 0; i < objsValue.Len(); i++ {
@@ -406,7 +655,7 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		listVal.Set(reflect.Append(listVal, objListValue.Index(i)))
 	}
 
-	if listVal.Len() >= lastLength {
+	if listVal.Len()+len(negativeKeys) >= lastLength {
 		return retList, nil
 	}
 
@@ -429,7 +678,7 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		}
 	}
 	absent := false
-	notifyInfo := base.MethodNotifyInfoMap[sqlMethodName]
+	absentParamArrays := make([][]interface{}, 0)
 	arrMap := base.getParamMap(paramArrays, notifyInfo)
 	objMap := make(map[string]interface{})
 	for i := 0; i < listVal.Len(); i++ {
@@ -438,8 +687,12 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 		objMap[objMapKey] = obj
 	}
 	for k := range arrMap {
+		if _, ok := negativeKeys[k]; ok {
+			continue
+		}
 		if _, ok := objMap[k]; !ok {
 			absent = true
+			absentParamArrays = append(absentParamArrays, arrMap[k])
 			for _, li := range listArgIndexs {
 				value := reflect.ValueOf(absentParams[li])
 				if value.Type().Kind() == reflect.Slice {
@@ -452,32 +705,28 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 			}
 		}
 	}
-	log.Logger.Debugf("current list: %v", listVal.Interface())
-	log.Logger.Debugf("absent params: %v, absent: %v", absentParams, absent)
-	for i := range absentParams {
-		log.Logger.Debugf("absentParams %d: %v", i, absentParams[i])
-	}
+	base.logger().Debug("GetByConcreteKeys absent params", log.Method(sqlMethodName), log.F("params", absentParams), log.F("absent", absent))
 
 	if absent {
-		absentRet := util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, absentParams...)
-		log.Logger.Debugf("absentRet %v", absentRet)
+		objs, err := base.invokeSQLDao(ctx, sqlMethodName, absentParams...)
 		if err != nil {
-			log.Logger.Errorf("get absent objs from sql failed, absent args: %v", absentParams)
+			base.logger().Error("GetByConcreteKeys load absent objects failed", log.Method(sqlMethodName), log.Args(absentParams), log.Err(err))
+			return nil, err
 		}
-		objs := absentRet[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
 		go func() {
-			err := base.SetCaches(objs, sqlMethodName, paramArrays) // here we pass paramArrays is ok, cause the implemention use map to find corresponding objs
+			err := base.SetCachesContext(context.Background(), objs, sqlMethodName, absentParamArrays) // only absent params, or the cached ones would be taken as absent
 			if err != nil {
-				log.Logger.Errorf("GetByConcreteKeys set absent caches failed for args: %v, err: %v", absentParams, err)
+				base.logger().Error("GetByConcreteKeys set absent caches failed", log.Method(sqlMethodName), log.Args(absentParams), log.Err(err))
 			}
 		}()
-		absentListType := reflect.TypeOf(objs)
-		absentListValue := reflect.ValueOf(objs)
-		if absentListType.Kind() == reflect.Ptr {
-			absentListValue = absentListValue.Elem()
-		}
-		for i := 0; i < absentListValue.Len(); i++ {
-			listVal.Set(reflect.Append(listVal, absentListValue.Index(i)))
+		if !util.IsNil(objs) {
+			absentListValue := reflect.ValueOf(objs)
+			if absentListValue.Kind() == reflect.Ptr {
+				absentListValue = absentListValue.Elem()
+			}
+			for i := 0; i < absentListValue.Len(); i++ {
+				listVal.Set(reflect.Append(listVal, absentListValue.Index(i)))
+			}
 		}
 	}
 
@@ -486,80 +735,269 @@ func (base *CacheDaoBase) GetByConcreteKeys(args ...interface{}) (interface{}, e
 
 // GetByRange range cache
 func (base *CacheDaoBase) GetByRange(args ...interface{}) (interface{}, error) {
-	sqlMethodName := util.GetLastExecuteFuncName()
+	return base.getByRange(context.Background(), util.GetLastExecuteFuncName(), args...)
+}
+
+// GetByRangeContext range cache, with context
+func (base *CacheDaoBase) GetByRangeContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return base.getByRange(ctx, util.GetLastExecuteFuncName(), args...)
+}
+
+func (base *CacheDaoBase) getByRange(ctx context.Context, sqlMethodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetByRange", attrMethod.String(sqlMethodName))
+	defer span.end(&err)
+	span.keys(1)
+	stats := base.stats(sqlMethodName).traced(span)
+	defer stats.since(time.Now())
 
 	// try to get from cache first.
-	cacheKey, err := base.GetKey(sqlMethodName, args...)
+	cacheKey, err := base.GetKeyContext(ctx, sqlMethodName, args...)
 	if err != nil || cacheKey == "" {
-		log.Logger.Warnf("1. GetByRange get cache key failed for args: %v, err: %v", args, err)
-		objList, err := base.SetListCache(sqlMethodName, args...)
-		if err != nil {
-			log.Logger.Errorf("GetByRange set cache failed for args: %v, err: %v", args, err)
-		}
-		return objList, nil
+		base.logMiss("GetByRange missed version", err, log.Method(sqlMethodName), log.Args(args))
+		stats.versionMiss(1)
+		return base.loadRange(ctx, sqlMethodName, args...)
 	}
 
 	// try to get from cache
-	cacheItem, err := MemcacheClient.Get(cacheKey)
+	cacheItem, err := base.getMethodCache(ctx, sqlMethodName, cacheKey)
 	if err != nil {
-		log.Logger.Warnf("2. GetByRange get cache failed for args: %v, err: %v", args, err)
-		objList, err := base.SetListCache(sqlMethodName, args...)
-		if err != nil {
-			log.Logger.Errorf("GetByRange set cache failed for args: %v, err: %v", args, err)
-		}
-		return objList, nil
+		base.logMiss("GetByRange missed cache", err, log.Method(sqlMethodName), log.Args(args))
+		stats.miss(1)
+		return base.loadRange(ctx, sqlMethodName, args...)
 	}
 
-	log.Logger.Debugf("GetByRange hit key %s", cacheKey)
+	base.logger().Debug("GetByRange hit cache", log.Method(sqlMethodName), log.Key(cacheKey))
+	stats.hit(1)
+	if isNegativeValue(cacheItem.Value) {
+		return base.makeObjListPtr(), nil
+	}
 
-	ids := make([]uint64, 0)
-	err = json.Unmarshal(cacheItem.Value, &ids)
+	keys, err := base.unmarshalKeys(cacheItem.Value)
 	if err != nil {
+		stats.deserializeFailure(1)
 		return nil, err
 	}
-	return base.GetByIds(ids)
+	return base.getByPrimaryKeys(ctx, keys)
+}
+
+// loadRange load range from sql and cache it, only the failure of sql is returned
+func (base *CacheDaoBase) loadRange(ctx context.Context, sqlMethodName string, args ...interface{}) (interface{}, error) {
+	objList, err := base.SetListCacheContext(ctx, sqlMethodName, args...)
+	if err != nil {
+		base.logger().Error("GetByRange set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+		if util.IsNil(objList) {
+			return nil, err
+		}
+	}
+	return objList, nil
 }
 
 // NotifyModified when do action like add/edit/delete, invoke this to update cache
 func (base *CacheDaoBase) NotifyModified(curDo interface{}) error {
+	return base.NotifyModifiedContext(context.Background(), curDo)
+}
+
+// NotifyModifiedContext when do action like add/edit/delete, invoke this to update cache, with context.
+// the error of invalidation is returned, the cache may be stale then.
+func (base *CacheDaoBase) NotifyModifiedContext(ctx context.Context, curDo interface{}) error {
 	if curDo == nil {
 		return nil
 	}
 
-	// delete object cache
-	id := base.GetIdValue(curDo)
-	objectKey, err := base.GetObjectKey(id)
+	key, versionKeys := base.makeInvalidation(curDo)
+	err := base.invalidate(ctx, key, versionKeys)
+	base.scheduleDelayedInvalidation(key, versionKeys)
+	return err
+}
+
+// NotifyUpdated invoke this after update, instead of 'NotifyModified', when the fields in notify keys may be changed.
+// the version keys of both old and new field values are updated. if before is nil, the cached object is taken as it.
+func (base *CacheDaoBase) NotifyUpdated(before, after interface{}) error {
+	return base.NotifyUpdatedContext(context.Background(), before, after)
+}
+
+// NotifyUpdatedContext invoke this after update when the fields in notify keys may be changed, with context
+func (base *CacheDaoBase) NotifyUpdatedContext(ctx context.Context, before, after interface{}) error {
+	if after == nil {
+		return base.NotifyModifiedContext(ctx, before)
+	}
+	if before == nil {
+		before = base.getCachedObject(ctx, base.GetPrimaryKey(after))
+	}
+
+	key, versionKeys := base.makeUpdateInvalidation(before, after)
+	err := base.invalidate(ctx, key, versionKeys)
+	base.scheduleDelayedInvalidation(key, versionKeys)
+	return err
+}
+
+// makeUpdateInvalidation get the id and version keys to invalidate for update,
+// the version keys of old field values are added if they are changed.
+func (base *CacheDaoBase) makeUpdateInvalidation(before, after interface{}) (interface{}, []string) {
+	key, versionKeys := base.makeInvalidation(after)
+	if before == nil {
+		return key, versionKeys
+	}
+	for _, info := range base.NotifyInfos {
+		if info.Type == constant.NotifyTypeRange {
+			// range version key doesn't contain field values
+			continue
+		}
+		beforeValues := util.GetFieldsStringValues(before, info.Fields)
+		afterValues := util.GetFieldsStringValues(after, info.Fields)
+		if strings.Join(beforeValues, "_") == strings.Join(afterValues, "_") {
+			continue
+		}
+		versionKeys = append(versionKeys, base.MakeVersionKey(info.VersionKeyPrefix, info, beforeValues))
+	}
+	return key, versionKeys
+}
+
+// getCachedObject get object from cache only, nil if absent
+func (base *CacheDaoBase) getCachedObject(ctx context.Context, key interface{}) interface{} {
+	if objInstancePtr, ok := base.getLocalObject(key); ok {
+		return objInstancePtr
+	}
+	objCacheKey, err := base.getObjectKey(ctx, key)
+	if err != nil || objCacheKey == "" {
+		return nil
+	}
+	objCacheItem, err := base.cacheStore().Get(ctx, objCacheKey)
 	if err != nil {
-		log.Logger.Errorf("Update single key field, id: %d err: %v", id, err)
+		return nil
 	}
-	log.Logger.Debugf("object key: %s", objectKey)
-	if objectKey != "" {
-		MemcacheClient.Delete(objectKey)
+	if isNegativeValue(objCacheItem.Value) {
+		return nil
+	}
+	objInstancePtr := base.makeObjInstancePtr()
+	if base.deserializeObject(objCacheItem.Value, objInstancePtr) != nil {
+		return nil
 	}
+	return objInstancePtr
+}
 
-	// update version cache
+// makeInvalidation get the primary key and version keys to invalidate for curDo
+func (base *CacheDaoBase) makeInvalidation(curDo interface{}) (interface{}, []string) {
+	versionKeys := make([]string, 0)
 	for _, info := range base.NotifyInfos {
 		fieldStrValues := util.GetFieldsStringValues(curDo, info.Fields)
-		vKey := base.MakeVersionKey(info.VersionKeyPrefix, info, fieldStrValues)
-		log.Logger.Debugf("ready to clear key: %s", vKey)
-		err := base.UpdateVersion(vKey)
-		if err != nil {
-			log.Logger.Error(err)
+		versionKeys = append(versionKeys, base.MakeVersionKey(info.VersionKeyPrefix, info, fieldStrValues))
+	}
+	return base.GetPrimaryKey(curDo), versionKeys
+}
+
+// invalidate delete object cache of key and update the version keys, all of them are tried,
+// the first error is returned.
+func (base *CacheDaoBase) invalidate(ctx context.Context, key interface{}, versionKeys []string) error {
+	var err error
+	ctx, span := base.startSpan(ctx, "Invalidate", attrVersionKeyCount.Int(len(versionKeys)))
+	defer span.end(nil)
+	span.id(key)
+
+	// delete object cache
+	base.removeLocalObject(key)
+	objectKey, keyErr := base.getObjectKey(ctx, key)
+	if keyErr != nil {
+		base.logger().Error("get object key for invalidation failed", log.Key(key), log.Err(keyErr))
+		span.fail(keyErr)
+		err = fmt.Errorf("get object key for invalidation failed: %w", keyErr)
+	}
+	base.logger().Debug("invalidate object cache", log.Key(objectKey))
+	if objectKey != "" {
+		base.cacheStore().Delete(ctx, objectKey)
+	}
+
+	// update version cache
+	for _, vKey := range versionKeys {
+		base.logger().Debug("update version", log.Key(vKey))
+		vErr := base.updateVersion(ctx, vKey)
+		if vErr != nil {
+			base.logger().Error("update version failed", log.Key(vKey), log.Err(vErr))
+			span.fail(vErr)
+			if err == nil {
+				err = fmt.Errorf("update version '%s' failed: %w", vKey, vErr)
+			}
 		}
 	}
-	return nil
+
+	base.publishInvalidation([]string{base.encodeKey(key)}, versionKeys)
+	return err
+}
+
+// scheduleDelayedInvalidation invalidate again after 'DelayedNotifyMillis',
+// clear the cache repopulated with stale data by the readers racing with the modification.
+func (base *CacheDaoBase) scheduleDelayedInvalidation(key interface{}, versionKeys []string) {
+	if base.DelayedNotifyMillis <= 0 {
+		return
+	}
+	time.AfterFunc(time.Duration(base.DelayedNotifyMillis)*time.Millisecond, func() {
+		base.invalidate(context.Background(), key, versionKeys)
+	})
 }
 
 // UpdateVersion update version
 func (base *CacheDaoBase) UpdateVersion(versionKey string) error {
-	now := time.Now().UnixNano() / 1e6
-	value := util.ConvertNumberToString(now)
-	return MemcacheClient.Set(&memcache.Item{Key: versionKey, Value: []byte(value), Expiration: int32(base.ExpireTime)})
+	return base.UpdateVersionContext(context.Background(), versionKey)
+}
+
+// UpdateVersionContext update version, with context
+func (base *CacheDaoBase) UpdateVersionContext(ctx context.Context, versionKey string) error {
+	err := base.updateVersion(ctx, versionKey)
+	if err != nil {
+		return fmt.Errorf("update version '%s' failed: %w", versionKey, err)
+	}
+	base.publishInvalidation(nil, []string{versionKey})
+	return nil
+}
+
+// maxUpdateVersionRetries times to retry the version bump modified concurrently
+const maxUpdateVersionRetries = 10
+
+// updateVersion bump version to now in milliseconds, or to the old one + 1 if it's not earlier than now,
+// so the version always changes even if it's bumped twice in a millisecond.
+func (base *CacheDaoBase) updateVersion(ctx context.Context, versionKey string) error {
+	store := base.cacheStore()
+	for i := 0; i < maxUpdateVersionRetries; i++ {
+		now := time.Now().UnixNano() / 1e6
+		item, err := store.Get(ctx, versionKey)
+		if err == ErrCacheMiss {
+			err = store.Add(ctx, &Item{Key: versionKey, Value: []byte(util.ConvertNumberToString(now)), Expiration: int32(base.ExpireTime)})
+			if err != ErrNotStored {
+				return err
+			}
+			// added by others
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if old := util.ConvertStringToNumber(string(item.Value)); old >= now {
+			now = old + 1
+		}
+		item.Value = []byte(util.ConvertNumberToString(now))
+		item.Expiration = int32(base.ExpireTime)
+		err = store.CAS(ctx, item)
+		if err != ErrCASConflict && err != ErrCacheMiss {
+			return err
+		}
+		// modified or deleted by others
+	}
+	return fmt.Errorf("version is modified by others %d times", maxUpdateVersionRetries)
 }
 
 // GetObjectKey 获取对象缓存key
 func (base *CacheDaoBase) GetObjectKey(id uint64) (string, error) {
-	version, err := base.GetObjectVersion(id)
+	return base.GetObjectKeyContext(context.Background(), id)
+}
+
+// GetObjectKeyContext 获取对象缓存key, with context
+func (base *CacheDaoBase) GetObjectKeyContext(ctx context.Context, id uint64) (string, error) {
+	return base.getObjectKey(ctx, id)
+}
+
+func (base *CacheDaoBase) getObjectKey(ctx context.Context, key interface{}) (string, error) {
+	version, err := base.getObjectVersion(ctx, key)
 	if err != nil {
 		return "", err
 	}
@@ -567,84 +1005,235 @@ func (base *CacheDaoBase) GetObjectKey(id uint64) (string, error) {
 		// version key missed
 		return "", nil
 	}
-	return base.MakeObjectKey(id, version), nil
+	return base.makeObjectKey(key, version), nil
 }
 
 // GetObjectKeys get object cache keys, if verision is absent, the result map will be absent too.
 func (base *CacheDaoBase) GetObjectKeys(ids []uint64) (map[uint64]string, error) {
-	versions, err := base.GetObjectVersions(ids)
+	return base.GetObjectKeysContext(context.Background(), ids)
+}
+
+// GetObjectKeysContext get object cache keys, if verision is absent, the result map will be absent too., with context
+func (base *CacheDaoBase) GetObjectKeysContext(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	objCacheKeys, err := base.getObjectKeys(ctx, idsToKeys(ids))
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range versions {
-		versions[k] = base.MakeObjectKey(k, v)
+	ret := make(map[uint64]string)
+	for i := range ids {
+		if v, ok := objCacheKeys[base.encodeKey(ids[i])]; ok {
+			ret[ids[i]] = v
+		}
 	}
-	return versions, nil
+	return ret, nil
+}
+
+// getObjectKeys get object cache keys mapped by encoded primary key
+func (base *CacheDaoBase) getObjectKeys(ctx context.Context, keys []interface{}) (map[string]string, error) {
+	versions, err := base.getObjectVersions(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string)
+	for i := range keys {
+		encodedKey := base.encodeKey(keys[i])
+		if v, ok := versions[encodedKey]; ok {
+			ret[encodedKey] = base.makeObjectKey(keys[i], v)
+		}
+	}
+	return ret, nil
 }
 
 // GetObjectVersion get object version from cache
 func (base *CacheDaoBase) GetObjectVersion(id uint64) (string, error) {
-	versionKey := base.MakeObjectVersionKey(id)
-	val, err := MemcacheClient.Get(versionKey)
-	if err == memcache.ErrCacheMiss {
+	return base.GetObjectVersionContext(context.Background(), id)
+}
+
+// GetObjectVersionContext get object version from cache, with context
+func (base *CacheDaoBase) GetObjectVersionContext(ctx context.Context, id uint64) (string, error) {
+	return base.getObjectVersion(ctx, id)
+}
+
+func (base *CacheDaoBase) getObjectVersion(ctx context.Context, key interface{}) (version string, err error) {
+	ctx, span := base.startSpan(ctx, "GetObjectVersion")
+	defer span.end(&err)
+	span.keys(1)
+
+	versionKey := base.makeObjectVersionKey(key)
+	val, err := base.cacheStore().Get(ctx, versionKey)
+	if err == ErrCacheMiss {
 		return "", nil
 	}
 	if err != nil {
 		return "", err
 	}
+	span.hit(1)
 	return string(val.Value), nil
 }
 
 // GetObjectVersions get object versions
 func (base *CacheDaoBase) GetObjectVersions(ids []uint64) (map[uint64]string, error) {
-	versionKeys := make([]string, 0)
+	return base.GetObjectVersionsContext(context.Background(), ids)
+}
+
+// GetObjectVersionsContext get object versions, with context
+func (base *CacheDaoBase) GetObjectVersionsContext(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	versions, err := base.getObjectVersions(ctx, idsToKeys(ids))
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[uint64]string)
 	for i := range ids {
-		versionKeys = append(versionKeys, base.MakeObjectVersionKey(ids[i]))
+		if v, ok := versions[base.encodeKey(ids[i])]; ok {
+			ret[ids[i]] = v
+		}
+	}
+	return ret, nil
+}
+
+// getObjectVersions get object versions mapped by encoded primary key
+func (base *CacheDaoBase) getObjectVersions(ctx context.Context, keys []interface{}) (versions map[string]string, err error) {
+	ctx, span := base.startSpan(ctx, "GetObjectVersions")
+	defer span.end(&err)
+	span.keys(len(keys))
+
+	versionKeys := make([]string, 0)
+	versionKeyMap := make(map[string]string) // version key -> encoded primary key
+	for i := range keys {
+		versionKey := base.makeObjectVersionKey(keys[i])
+		versionKeys = append(versionKeys, versionKey)
+		versionKeyMap[versionKey] = base.encodeKey(keys[i])
 	}
-	val, err := MemcacheClient.GetMulti(versionKeys)
+	val, err := base.cacheStore().GetMulti(ctx, versionKeys)
 	if err != nil {
 		return nil, err
 	}
-	ret := make(map[uint64]string)
+	span.hit(len(val))
+	ret := make(map[string]string)
 	for k, v := range val {
-		id := base.ResolveIdFromObjectVersionKey(k)
-		ret[id] = string(v.Value)
+		ret[versionKeyMap[k]] = string(v.Value)
 	}
 	return ret, nil
 }
 
+// getMethodCache get the cache of method by cache key
+func (base *CacheDaoBase) getMethodCache(ctx context.Context, methodName string, cacheKey string) (item *Item, err error) {
+	ctx, span := base.startSpan(ctx, "GetCache", attrMethod.String(methodName))
+	defer span.end(&err)
+	span.keys(1)
+
+	item, err = base.cacheStore().Get(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	span.hit(1)
+	return item, nil
+}
+
+// getMethodCaches get the caches of method by cache keys
+func (base *CacheDaoBase) getMethodCaches(ctx context.Context, methodName string, cacheKeys []string) (items map[string]*Item, err error) {
+	ctx, span := base.startSpan(ctx, "GetCaches", attrMethod.String(methodName))
+	defer span.end(&err)
+	span.keys(len(cacheKeys))
+
+	items, err = base.cacheStore().GetMulti(ctx, cacheKeys)
+	if err != nil {
+		return nil, err
+	}
+	span.hit(len(items))
+	return items, nil
+}
+
+// getObjectCache get object cache by object cache key
+func (base *CacheDaoBase) getObjectCache(ctx context.Context, objCacheKey string) (item *Item, err error) {
+	ctx, span := base.startSpan(ctx, "GetObjectCache")
+	defer span.end(&err)
+	span.keys(1)
+
+	item, err = base.cacheStore().Get(ctx, objCacheKey)
+	if err != nil {
+		return nil, err
+	}
+	span.hit(1)
+	return item, nil
+}
+
+// getObjectCaches get object caches by object cache keys
+func (base *CacheDaoBase) getObjectCaches(ctx context.Context, objCacheKeys []string) (items map[string]*Item, err error) {
+	ctx, span := base.startSpan(ctx, "GetObjectCaches")
+	defer span.end(&err)
+	span.keys(len(objCacheKeys))
+
+	items, err = base.cacheStore().GetMulti(ctx, objCacheKeys)
+	if err != nil {
+		return nil, err
+	}
+	span.hit(len(items))
+	return items, nil
+}
+
 // MakeObjectKey make object key string
 func (base *CacheDaoBase) MakeObjectKey(id uint64, version string) string {
-	return fmt.Sprintf("%s_%d_%s", base.ObjectCachePrefix, id, version)
+	return base.makeObjectKey(id, version)
+}
+
+func (base *CacheDaoBase) makeObjectKey(key interface{}, version string) string {
+	return fmt.Sprintf("%s_%s_%s", base.ObjectCachePrefix, base.encodeKey(key), version)
 }
 
 // MakeObjectVersionKey make object version key string
 func (base *CacheDaoBase) MakeObjectVersionKey(id uint64) string {
-	return fmt.Sprintf("V_%s_%d", base.ObjectCachePrefix, id)
+	return base.makeObjectVersionKey(id)
+}
+
+func (base *CacheDaoBase) makeObjectVersionKey(key interface{}) string {
+	return fmt.Sprintf("V_%s_%s", base.ObjectCachePrefix, base.encodeKey(key))
 }
 
 // ResolveIdFromObjectVersionKey resolve id from verison key
+//
+// Deprecated: works for integer primary key only, keys are mapped back to primary keys where they are made now.
 func (base *CacheDaoBase) ResolveIdFromObjectVersionKey(versionKey string) uint64 {
 	ps := strings.Split(versionKey, "_")
 	return util.ConvertStringToUNumber(ps[len(ps)-1])
 }
 
 // ResolveIdFromObjectCacheKey resolve id from object cache key
+//
+// Deprecated: works for integer primary key only, keys are mapped back to primary keys where they are made now.
 func (base *CacheDaoBase) ResolveIdFromObjectCacheKey(cacheKey string) uint64 {
 	ps := strings.Split(cacheKey, "_")
 	return util.ConvertStringToUNumber(ps[len(ps)-2])
 }
 
-// SetBojectCacheForGetById helpful for the scene when we get obj from id and then update cache.
+// SetObjectCacheForGetById helpful for the scene when we get obj from id and then update cache.
 func (base *CacheDaoBase) SetObjectCacheForGetById(id uint64) (interface{}, error) {
-	obj, err := base.sqlGetById(id)
+	return base.SetObjectCacheForGetByIdContext(context.Background(), id)
+}
+
+// SetObjectCacheForGetByIdContext helpful for the scene when we get obj from id and then update cache., with context
+func (base *CacheDaoBase) SetObjectCacheForGetByIdContext(ctx context.Context, id uint64) (interface{}, error) {
+	return base.setObjectCacheForKey(ctx, id)
+}
+
+func (base *CacheDaoBase) setObjectCacheForKey(ctx context.Context, key interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "LoadObject")
+	defer span.end(&err)
+	span.absent([]interface{}{key})
+
+	obj, err := base.sqlGetByKey(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 	if obj != nil {
-		err = base.SetObjectCache(obj)
+		err = base.setObjectCache(ctx, obj, metricsMethodGetById)
+		if err != nil {
+			base.logger().Error("set object cache failed", log.Key(key), log.Err(err))
+		}
+	} else {
+		err = base.setNegativeObjectCache(ctx, key)
 		if err != nil {
-			log.Logger.Error("set cache failed for id %d, obj: %v", id, obj)
+			base.logger().Error("set negative object cache failed", log.Key(key), log.Err(err))
 		}
 	}
 	return obj, nil
@@ -652,47 +1241,88 @@ func (base *CacheDaoBase) SetObjectCacheForGetById(id uint64) (interface{}, erro
 
 // SetObjectCachesForGetByIds helpful for the scene when we get objs from ids and then update cache.
 func (base *CacheDaoBase) SetObjectCachesForGetByIds(ids []uint64) (interface{}, error) {
-	objList, err := base.sqlGetByIds(ids)
+	return base.SetObjectCachesForGetByIdsContext(context.Background(), ids)
+}
+
+// SetObjectCachesForGetByIdsContext helpful for the scene when we get objs from ids and then update cache., with context
+func (base *CacheDaoBase) SetObjectCachesForGetByIdsContext(ctx context.Context, ids []uint64) (interface{}, error) {
+	return base.setObjectCachesForKeys(ctx, idsToKeys(ids))
+}
+
+func (base *CacheDaoBase) setObjectCachesForKeys(ctx context.Context, keys []interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "LoadObjects")
+	defer span.end(&err)
+	span.absent(keys)
+
+	objList, err := base.sqlGetByKeys(ctx, keys)
 	if err != nil {
 		return nil, err
 	}
-	go base.SetOjectCaches(objList)
+	go func() {
+		base.SetOjectCachesContext(context.Background(), objList)
+		base.setNegativeObjectCaches(context.Background(), keys, objList)
+	}()
 	return objList, nil
 }
 
 // SetObjectCache set object cache for obj
 func (base *CacheDaoBase) SetObjectCache(obj interface{}) error {
-	id := base.GetIdValue(obj)
+	return base.SetObjectCacheContext(context.Background(), obj)
+}
+
+// SetObjectCacheContext set object cache for obj, with context
+func (base *CacheDaoBase) SetObjectCacheContext(ctx context.Context, obj interface{}) error {
+	return base.setObjectCache(ctx, obj, metricsMethodGetById)
+}
+
+// setObjectCache set object cache for obj loaded by method, the failure is reported under the method
+func (base *CacheDaoBase) setObjectCache(ctx context.Context, obj interface{}, methodName string) (err error) {
+	key := base.GetPrimaryKey(obj)
+	ctx, span := base.startSpan(ctx, "SetObjectCache")
+	defer span.end(&err)
+	span.id(key)
 
 	// set cache first, that promise before obj stored successfully,
 	// old cache can be readed from cache, it decrease the query amount
 	// through DB.
 	now := time.Now().UnixNano() / 1e6
-	objCacheKey := base.MakeObjectKey(id, util.ConvertNumberToString(now))
+	objCacheKey := base.makeObjectKey(key, util.ConvertNumberToString(now))
 
-	objData, err := base.Serializer.Serialize(obj)
+	objData, err := base.serializeObject(obj)
 	if err != nil {
 		return err
 	}
 
-	err = MemcacheClient.Set(&memcache.Item{Key: objCacheKey, Value: objData, Expiration: int32(base.ExpireTime)})
+	err = base.cacheStore().Set(ctx, &Item{Key: objCacheKey, Value: objData, Expiration: int32(base.ExpireTime)})
 	if err != nil {
+		base.stats(methodName).setFailure(1)
 		return err
 	}
+	base.setLocalObject(key, objData)
 
 	// update version cache then, it's safe if version key set failed.
-	return base.SetObjectVersion(id, now)
+	return base.setObjectVersion(ctx, key, now)
 }
 
 // SetOjectCaches set object caches for obj list
 func (base *CacheDaoBase) SetOjectCaches(objList interface{}) {
+	base.SetOjectCachesContext(context.Background(), objList)
+}
+
+// SetOjectCachesContext set object caches for obj list, with context
+func (base *CacheDaoBase) SetOjectCachesContext(ctx context.Context, objList interface{}) {
+	base.setObjectCaches(ctx, objList, metricsMethodGetByIds)
+}
+
+// setObjectCaches set object caches for obj list loaded by method
+func (base *CacheDaoBase) setObjectCaches(ctx context.Context, objList interface{}, methodName string) {
 	listValue := reflect.ValueOf(objList).Elem()
 	if listValue.Len() > 0 {
 		for i := 0; i < listValue.Len(); i++ {
 			obj := listValue.Index(i).Interface()
-			err := base.SetObjectCache(obj)
+			err := base.setObjectCache(ctx, obj, methodName)
 			if err != nil {
-				log.Logger.Error("set cache failed for obj: %v when set object caches", obj)
+				base.logger().Error("set object cache failed", log.Key(base.GetPrimaryKey(obj)), log.Err(err))
 			}
 		}
 	}
@@ -700,14 +1330,28 @@ func (base *CacheDaoBase) SetOjectCaches(objList interface{}) {
 
 // SetObjectVersion set version cache
 func (base *CacheDaoBase) SetObjectVersion(id uint64, ts int64) error {
-	objVersionKey := base.MakeObjectVersionKey(id)
-	return MemcacheClient.Set(&memcache.Item{Key: objVersionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
+	return base.SetObjectVersionContext(context.Background(), id, ts)
+}
+
+// SetObjectVersionContext set version cache, with context
+func (base *CacheDaoBase) SetObjectVersionContext(ctx context.Context, id uint64, ts int64) error {
+	return base.setObjectVersion(ctx, id, ts)
+}
+
+func (base *CacheDaoBase) setObjectVersion(ctx context.Context, key interface{}, ts int64) error {
+	objVersionKey := base.makeObjectVersionKey(key)
+	return base.cacheStore().Set(ctx, &Item{Key: objVersionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
 }
 
 // GetKey get cache key
 func (base *CacheDaoBase) GetKey(methodName string, args ...interface{}) (string, error) {
+	return base.GetKeyContext(context.Background(), methodName, args...)
+}
+
+// GetKeyContext get cache key, with context
+func (base *CacheDaoBase) GetKeyContext(ctx context.Context, methodName string, args ...interface{}) (string, error) {
 	// Version first
-	version, err := base.GetVersion(methodName, args...)
+	version, err := base.GetVersionContext(ctx, methodName, args...)
 	if err != nil {
 		return "", err
 	}
@@ -723,24 +1367,43 @@ func (base *CacheDaoBase) GetKey(methodName string, args ...interface{}) (string
 
 // GetVersion get current version
 func (base *CacheDaoBase) GetVersion(methodName string, args ...interface{}) (string, error) {
+	return base.GetVersionContext(context.Background(), methodName, args...)
+}
+
+// GetVersionContext get current version, with context
+func (base *CacheDaoBase) GetVersionContext(ctx context.Context, methodName string, args ...interface{}) (version string, err error) {
+	ctx, span := base.startSpan(ctx, "GetVersion", attrMethod.String(methodName))
+	defer span.end(&err)
+	span.keys(1)
+
 	// get method info
 	versionKey, err := base.MakeMethodVersionKey(methodName, args...)
 	if err != nil {
 		return "", err
 	}
 
-	item, err := MemcacheClient.Get(versionKey)
-	if err == memcache.ErrCacheMiss {
+	item, err := base.cacheStore().Get(ctx, versionKey)
+	if err == ErrCacheMiss {
 		return "", nil
 	}
 	if err != nil {
 		return "", err
 	}
+	span.hit(1)
 	return string(item.Value), nil
 }
 
 // GetVersions get the version of multi args
 func (base *CacheDaoBase) GetVersions(methodName string, args [][]interface{}) (map[string]string, error) {
+	return base.GetVersionsContext(context.Background(), methodName, args)
+}
+
+// GetVersionsContext get the version of multi args, with context
+func (base *CacheDaoBase) GetVersionsContext(ctx context.Context, methodName string, args [][]interface{}) (versions map[string]string, err error) {
+	ctx, span := base.startSpan(ctx, "GetVersions", attrMethod.String(methodName))
+	defer span.end(&err)
+	span.keys(len(args))
+
 	ret := make(map[string]string)
 	// make version keys
 	versionMap := make(map[string]string)
@@ -749,22 +1412,20 @@ func (base *CacheDaoBase) GetVersions(methodName string, args [][]interface{}) (
 		akey := base.JoinArgs(methodName, args[i]...)
 		versionKey, err := base.MakeMethodVersionKey(methodName, args[i]...)
 		if err != nil {
-			log.Logger.Errorf("make versioin key failed, err: %v", err)
+			base.logger().Error("make version key failed", log.Method(methodName), log.Args(args[i]), log.Err(err))
 			continue
 		}
 		versionKeys = append(versionKeys, versionKey)
 		versionMap[versionKey] = akey
 	}
 
-	log.Logger.Debugf("version keys: %v", versionKeys)
-	log.Logger.Debugf("version map: %v", versionMap)
-
-	startTime := time.Now().UnixNano() / 1e6
-	items, err := MemcacheClient.GetMulti(versionKeys)
-	log.Logger.Warnf("GetVersions get multi cost %d", time.Now().UnixNano()/1e6-startTime)
+	startTime := time.Now()
+	items, err := base.cacheStore().GetMulti(ctx, versionKeys)
+	base.logger().Debug("get versions", log.Method(methodName), log.Key(versionKeys), log.Latency(startTime))
 	if err != nil {
 		return ret, err
 	}
+	span.hit(len(items))
 
 	for k, v := range items {
 		ret[versionMap[k]] = string(v.Value)
@@ -777,7 +1438,7 @@ func (base *CacheDaoBase) MakeMethodVersionKey(methodName string, args ...interf
 	// get method info
 	info, ok := base.MethodNotifyInfoMap[methodName]
 	if !ok {
-		log.Logger.Warnf("no such method '%s' mapped info", methodName)
+		base.logger().Warn("no notify info of method", log.Method(methodName))
 		return "", fmt.Errorf("no such method '%s' mapped info", methodName)
 	}
 
@@ -789,29 +1450,38 @@ func (base *CacheDaoBase) MakeMethodVersionKey(methodName string, args ...interf
 	}
 
 	versionKey := base.MakeVersionKey(info.VersionKeyPrefix, info, keyArgs)
-	log.Logger.Debugf("Maked version key: %s", versionKey)
 	return versionKey, nil
 }
 
 // SetVersion set version cache
 func (base *CacheDaoBase) SetVersion(methodName string, ts int64, args ...interface{}) error {
+	return base.SetVersionContext(context.Background(), methodName, ts, args...)
+}
+
+// SetVersionContext set version cache, with context
+func (base *CacheDaoBase) SetVersionContext(ctx context.Context, methodName string, ts int64, args ...interface{}) error {
 	// get method info
 	versionKey, err := base.MakeMethodVersionKey(methodName, args...)
 	if err != nil {
 		return err
 	}
-	return MemcacheClient.Set(&memcache.Item{Key: versionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
+	return base.cacheStore().Set(ctx, &Item{Key: versionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
 }
 
 // AddVersion set version cache
 func (base *CacheDaoBase) AddVersion(methodName string, ts int64, args ...interface{}) error {
+	return base.AddVersionContext(context.Background(), methodName, ts, args...)
+}
+
+// AddVersionContext set version cache, with context
+func (base *CacheDaoBase) AddVersionContext(ctx context.Context, methodName string, ts int64, args ...interface{}) error {
 	// get method info
 	versionKey, err := base.MakeMethodVersionKey(methodName, args...)
 	if err != nil {
 		return err
 	}
-	err = MemcacheClient.Add(&memcache.Item{Key: versionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
-	if err == memcache.ErrNotStored {
+	err = base.cacheStore().Add(ctx, &Item{Key: versionKey, Value: []byte(util.ConvertNumberToString(ts)), Expiration: int32(base.ExpireTime)})
+	if err == ErrNotStored {
 		return nil
 	}
 	return err
@@ -819,58 +1489,97 @@ func (base *CacheDaoBase) AddVersion(methodName string, ts int64, args ...interf
 
 // SetCache set cache for key query
 func (base *CacheDaoBase) SetCache(obj interface{}, methodName string, args ...interface{}) error {
-	idVal := base.GetIdValue(obj)
-	now := time.Now().UnixNano() / 1e6
+	return base.SetCacheContext(context.Background(), obj, methodName, args...)
+}
+
+// SetCacheContext set cache for key query, with context
+// nil obj means no row matched, which is cached as absent if 'NegativeExpireTime' is set.
+func (base *CacheDaoBase) SetCacheContext(ctx context.Context, obj interface{}, methodName string, args ...interface{}) error {
+	if util.IsNil(obj) {
+		return base.setNegativeCache(ctx, methodName, args...)
+	}
+	key := base.GetPrimaryKey(obj)
 
 	// set object cache
-	err := base.SetObjectCache(obj)
+	err := base.setObjectCache(ctx, obj, methodName)
+	if err != nil {
+		base.logger().Error("set object cache failed", log.Key(key), log.Err(err))
+	}
 
 	// set cache
-	oldVersion, err := base.GetVersion(methodName, args...)
+	keyData, err := base.marshalKey(key)
+	if err != nil {
+		return err
+	}
+	return base.setMethodCache(ctx, base.MakeKeyPrefix(methodName, args...), keyData, base.ExpireTime, methodName, args...)
+}
+
+// setMethodCache set value as the cache of method with args under current version, the cache key is made from keyPrefix
+func (base *CacheDaoBase) setMethodCache(ctx context.Context, keyPrefix string, value []byte, expireTime int, methodName string, args ...interface{}) (err error) {
+	ctx, span := base.startSpan(ctx, "SetCache", attrMethod.String(methodName))
+	defer span.end(&err)
+
+	now := time.Now().UnixNano() / 1e6
+	oldVersion, err := base.GetVersionContext(ctx, methodName, args...)
 	if err != nil {
 		return err
 	}
 	if oldVersion != "" {
 		now = util.ConvertStringToNumber(oldVersion)
 	}
-	keyPrefix := base.MakeKeyPrefix(methodName, args...)
 	cacheKey := base.MakeKey(keyPrefix, util.ConvertNumberToString(now))
 
-	err = MemcacheClient.Set(&memcache.Item{Key: cacheKey, Value: []byte(util.ConvertUNumberToString(idVal)), Expiration: int32(base.ExpireTime)})
+	err = base.cacheStore().Set(ctx, &Item{Key: cacheKey, Value: value, Expiration: int32(expireTime)})
 	if err != nil {
+		base.stats(methodName).setFailure(1)
 		return err
 	}
 
 	// set version cache, if existed already, ignore
-	err = base.AddVersion(methodName, now, args...)
-	return err
+	return base.AddVersionContext(ctx, methodName, now, args...)
 }
 
 // SetCaches set caches for keys query
 func (base *CacheDaoBase) SetCaches(objs interface{}, methodName string, paramArray [][]interface{}) error {
+	return base.SetCachesContext(context.Background(), objs, methodName, paramArray)
+}
 
-	log.Logger.Debugf("SetCaches objs: %v", objs)
-	log.Logger.Debugf("SetCaches methodName: %v", methodName)
+// SetCachesContext set caches for keys query, with context
+func (base *CacheDaoBase) SetCachesContext(ctx context.Context, objs interface{}, methodName string, paramArray [][]interface{}) error {
 
-	// set each key cache
-	objsValue := reflect.ValueOf(objs)
-	objsType := reflect.TypeOf(objs)
-	if objsType.Kind() == reflect.Ptr {
-		objsType = objsType.Elem()
-		objsValue = objsValue.Elem()
-	}
+	base.logger().Debug("SetCaches", log.Method(methodName), log.F("objs", objs))
 
 	notifyInfo := base.MethodNotifyInfoMap[methodName]
 	arrMap := base.getParamMap(paramArray, notifyInfo)
-	log.Logger.Debugf("arrMap: %v", arrMap)
 
-	for i := 0; i < objsValue.Len(); i++ {
-		obj := objsValue.Index(i).Interface()
-		objMapKey := base.getObjMapKey(obj, notifyInfo)
-		log.Logger.Debugf("objMapKey: %s", objMapKey)
-		if param, ok := arrMap[objMapKey]; ok {
-			log.Logger.Debugf("cache match for %v", param)
-			base.SetCache(obj, methodName, param...)
+	// set each key cache
+	matched := make(map[string]int)
+	if !util.IsNil(objs) {
+		objsValue := reflect.ValueOf(objs)
+		if objsValue.Kind() == reflect.Ptr {
+			objsValue = objsValue.Elem()
+		}
+		for i := 0; i < objsValue.Len(); i++ {
+			obj := objsValue.Index(i).Interface()
+			objMapKey := base.getObjMapKey(obj, notifyInfo)
+			if param, ok := arrMap[objMapKey]; ok {
+				base.logger().Debug("SetCaches match", log.Method(methodName), log.Args(param))
+				matched[objMapKey] = 1
+				base.SetCacheContext(ctx, obj, methodName, param...)
+			}
+		}
+	}
+
+	// the params matched nothing are absent
+	if base.negativeCacheEnabled() {
+		for k, param := range arrMap {
+			if _, ok := matched[k]; ok {
+				continue
+			}
+			err := base.setNegativeCache(ctx, methodName, param...)
+			if err != nil {
+				base.logger().Error("set negative cache failed", log.Method(methodName), log.Args(param), log.Err(err))
+			}
 		}
 	}
 
@@ -879,52 +1588,46 @@ func (base *CacheDaoBase) SetCaches(objs interface{}, methodName string, paramAr
 
 // SetListCache set list cache
 func (base *CacheDaoBase) SetListCache(methodName string, args ...interface{}) (interface{}, error) {
+	return base.SetListCacheContext(context.Background(), methodName, args...)
+}
+
+// SetListCacheContext set list cache, with context
+func (base *CacheDaoBase) SetListCacheContext(ctx context.Context, methodName string, args ...interface{}) (interface{}, error) {
 	err := base.dbArgCheck(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	// replace the first arg (we assume it's gorm.DB) with Select.('ID')
+	// replace the first arg (we assume it's gorm.DB) with Select of primary key columns
 	copyArgs := make([]interface{}, len(args))
 	for i := range args {
 		copyArgs[i] = args[i]
 	}
-	copyArgs[0] = base.ReadDBSource.Select(base.IDFieldName)
-	retVals := util.ReflectInvokeMethod(base.SQLDao, methodName, copyArgs...)
-	objs := retVals[0] // TODO: 这里目前默认是第一个返回值作为db obj, 后续评估是否需要扫描结果数组
-	ids, err := base.GetIdsValue(objs)
+	copyArgs[0] = base.ReadDBSource.WithContext(ctx).Select(base.primaryColumns())
+	objs, err := base.invokeSQLDao(ctx, methodName, copyArgs...)
 	if err != nil {
 		return nil, err
 	}
-
-	retList, err := base.GetByIds(ids)
+	keys, err := base.getPrimaryKeys(objs)
 	if err != nil {
 		return nil, err
 	}
 
-	// then we fall to get by ids
-	now := time.Now().UnixNano() / 1e6
-	oldVersion, err := base.GetVersion(methodName, args...)
+	retList, err := base.getByPrimaryKeys(ctx, keys)
 	if err != nil {
-		return retList, err
-	}
-	if oldVersion != "" {
-		now = util.ConvertStringToNumber(oldVersion)
+		return nil, err
 	}
-	keyPrefix := base.MakeKeyPrefix(methodName, args...)
-	cacheKey := base.MakeKey(keyPrefix, util.ConvertNumberToString(now))
 
-	idsJSON, err := json.Marshal(&ids)
-	if err != nil {
-		return retList, err
+	// empty list is cached for a short time as absent
+	if len(keys) == 0 && base.negativeCacheEnabled() {
+		return retList, base.setNegativeCache(ctx, methodName, args...)
 	}
-	err = MemcacheClient.Set(&memcache.Item{Key: cacheKey, Value: idsJSON, Expiration: int32(base.ExpireTime)})
+
+	keysJSON, err := json.Marshal(keys)
 	if err != nil {
 		return retList, err
 	}
-
-	// set version cache, if existed already, ignore
-	err = base.AddVersion(methodName, now, args...)
+	err = base.setMethodCache(ctx, base.MakeKeyPrefix(methodName, args...), keysJSON, base.ExpireTime, methodName, args...)
 	return retList, err
 }
 
@@ -951,12 +1654,11 @@ func (base *CacheDaoBase) MakeKeyPrefix(methodName string, args ...interface{})
 	argsStr = append(argsStr, methodName)
 	argsName := util.GetMetodParameterList(base.SQLDao, methodName)
 	for i := range argsName {
-		if argsName[i] == "gorm.io/gorm_DB" {
+		if argsName[i] == "gorm.io/gorm_DB" || argsName[i] == "context_Context" {
 			continue
 		}
 		argsStr = append(argsStr, util.GeneralToString(args[i]))
 	}
-	log.Logger.Debugf("Key prefix is: %s", strings.Join(argsStr, "_"))
 	return strings.Join(argsStr, "_")
 }
 
@@ -967,7 +1669,7 @@ func (base *CacheDaoBase) JoinArgs(methodName string, args ...interface{}) strin
 	var ret string = ""
 	argsName := util.GetMetodParameterList(base.SQLDao, methodName)
 	for i := range argsName {
-		if argsName[i] == "gorm.io/gorm_DB" {
+		if argsName[i] == "gorm.io/gorm_DB" || argsName[i] == "context_Context" {
 			continue
 		}
 		ret += util.GeneralToString(args[i]) + "_"
@@ -975,17 +1677,16 @@ func (base *CacheDaoBase) JoinArgs(methodName string, args ...interface{}) strin
 	return ret[0 : len(ret)-1]
 }
 
-// GetIdValue get the id value of object
+// GetIdValue get the id value of object, 0 if the primary key isn't integer, use 'GetPrimaryKey' instead for that
 func (base *CacheDaoBase) GetIdValue(do interface{}) uint64 {
-	val := util.GetSpecifiedFieldValue(do, base.IDFieldName)
-	valType := reflect.TypeOf(val)
-	var id uint64
-	if valType.Kind() == reflect.Int64 {
-		id = uint64(val.(int64))
-	} else if valType.Kind() == reflect.Uint64 {
-		id = val.(uint64)
-	}
-	return id
+	val := reflect.ValueOf(util.GetSpecifiedFieldValue(do, base.IDFieldName))
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint()
+	}
+	return 0
 }
 
 // GetIdsValue get ids list from do list
@@ -1023,24 +1724,22 @@ func (base *CacheDaoBase) dbArgCheck(args ...interface{}) error {
 	return nil
 }
 
-// reorderByIds reorder objList by ids order
-func (base *CacheDaoBase) reorderByIds(ids []uint64, objList interface{}) interface{} {
+// reorderByKeys reorder objList by keys order
+func (base *CacheDaoBase) reorderByKeys(keys []interface{}, objList interface{}) interface{} {
 	orderedList := base.makeObjListPtr()
 
-	objMap := make(map[uint64]reflect.Value)
+	objMap := make(map[string]reflect.Value)
 	objListValue := reflect.ValueOf(objList).Elem()
 	for i := 0; i < objListValue.Len(); i++ {
 		obj := objListValue.Index(i).Interface()
-		id := base.GetIdValue(obj)
-		objMap[id] = objListValue.Index(i)
+		objMap[base.encodeKey(base.GetPrimaryKey(obj))] = objListValue.Index(i)
 	}
 
 	orderedListValue := reflect.ValueOf(orderedList).Elem()
-	for i := range ids {
-		if v, ok := objMap[ids[i]]; ok {
+	for i := range keys {
+		if v, ok := objMap[base.encodeKey(keys[i])]; ok {
 			orderedListValue.Set(reflect.Append(orderedListValue, v))
 		}
-
 	}
 	return orderedList
 }
@@ -1067,17 +1766,114 @@ func (base *CacheDaoBase) getObjMapKey(obj interface{}, notifyInfo *NotifyInfo)
 func (base *CacheDaoBase) getParamMap(args [][]interface{}, notifyInfo *NotifyInfo) map[string][]interface{} {
 	arrMap := make(map[string][]interface{})
 	for i := range args {
-		key := ""
-		for _, j := range notifyInfo.Args {
-			key += (util.GeneralToString(args[i][j]) + "_")
-		}
-		arrMap[key[0:len(key)-1]] = args[i]
+		arrMap[base.getParamMapKey(args[i], notifyInfo)] = args[i]
 	}
 	return arrMap
 }
 
+func (base *CacheDaoBase) getParamMapKey(args []interface{}, notifyInfo *NotifyInfo) string {
+	key := ""
+	for _, j := range notifyInfo.Args {
+		key += (util.GeneralToString(args[j]) + "_")
+	}
+	return key[0 : len(key)-1]
+}
+
+// cacheStore get the cache store of this dao
+func (base *CacheDaoBase) cacheStore() CacheStore {
+	if base.Store != nil {
+		return base.Store
+	}
+	return DefaultStore
+}
+
+// logger get the logger of this dao, logs with the dao name
+func (base *CacheDaoBase) logger() log.Interface {
+	if base.daoLogger != nil {
+		return base.daoLogger
+	}
+	// not initialized yet
+	return base.newLogger()
+}
+
+func (base *CacheDaoBase) newLogger() log.Interface {
+	logger := base.Logger
+	if logger == nil {
+		logger = log.Default
+	}
+	return log.With(logger, log.Dao(base.ObjectCachePrefix))
+}
+
+// logMiss log cache miss at debug level, as it's expected, but the failure of cache store at warn level
+func (base *CacheDaoBase) logMiss(msg string, err error, fields ...log.Field) {
+	if err == nil || errors.Is(err, ErrCacheMiss) {
+		base.logger().Debug(msg, fields...)
+		return
+	}
+	base.logger().Warn(msg, append(fields, log.Err(err))...)
+}
+
 /* ------ below is addtional sql method helper ------- */
 
+// invokeSQLDao invoke sql dao method, bind ctx to the 'gorm.DB' args.
+// the args are untouched when invoked by method without context, so the context set on 'gorm.DB' by caller is kept.
+// the first return value is the result, and the trailing 'error' return value (if has) is returned as error,
+// 'gorm.ErrRecordNotFound' is taken as nil result rather than error.
+func (base *CacheDaoBase) invokeSQLDao(ctx context.Context, methodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "SQLDao", attrMethod.String(methodName))
+	defer span.end(&err)
+
+	base.stats(methodName).dbFallback(1)
+	if isBackground(ctx) {
+		return sqlDaoResult(util.ReflectInvokeMethod(base.SQLDao, methodName, args...))
+	}
+	ctxArgs := make([]interface{}, len(args))
+	for i := range args {
+		if db, ok := args[i].(*gorm.DB); ok && db != nil {
+			ctxArgs[i] = db.WithContext(ctx)
+		} else {
+			ctxArgs[i] = args[i]
+		}
+	}
+	return sqlDaoResult(util.ReflectInvokeMethod(base.SQLDao, methodName, ctxArgs...))
+}
+
+// sqlDaoResult get result and error from the return values of sql dao method
+func sqlDaoResult(retVals []interface{}) (interface{}, error) {
+	if len(retVals) == 0 {
+		return nil, errors.New("sql dao method has no return value")
+	}
+	if len(retVals) > 1 {
+		err, _ := retVals[len(retVals)-1].(error)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return retVals[0], nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// checkSQLMethod check the signature of cached sql dao method,
+// the first return value is the result, and there can be a trailing 'error' return value.
+func (base *CacheDaoBase) checkSQLMethod(methodName string) error {
+	method := reflect.ValueOf(base.SQLDao).MethodByName(methodName)
+	if !method.IsValid() {
+		return fmt.Errorf("sql dao has no method '%s'", methodName)
+	}
+	methodType := method.Type()
+	if methodType.NumOut() == 0 || methodType.Out(0) == errorType {
+		return fmt.Errorf("sql dao method '%s' should return the result as the first return value", methodName)
+	}
+	if methodType.NumOut() > 1 && methodType.Out(methodType.NumOut()-1) != errorType {
+		return fmt.Errorf("the last return value of sql dao method '%s' should be error, got %v", methodName, methodType.Out(methodType.NumOut()-1))
+	}
+	return nil
+}
+
 func (base *CacheDaoBase) makeObjInstancePtr() interface{} {
 	doType := reflect.TypeOf(base.Do)
 	if doType.Kind() == reflect.Ptr {
@@ -1094,11 +1890,12 @@ func (base *CacheDaoBase) makeObjListPtr() interface{} {
 	return reflect.New(reflect.SliceOf(doType)).Interface()
 }
 
-func (base *CacheDaoBase) sqlGetById(id uint64) (interface{}, error) {
+func (base *CacheDaoBase) sqlGetByKey(ctx context.Context, key interface{}) (interface{}, error) {
+	base.stats(metricsMethodGetById).dbFallback(1)
 	ret := base.makeObjInstancePtr()
-	db := base.ReadDBSource.Model(ret)
+	db := base.ReadDBSource.WithContext(ctx).Table(base.schema.Table).Model(ret)
 
-	err := db.Where("id=?", id).First(ret).Error
+	err := db.Where(base.primaryKeysCondition([]interface{}{key})).First(ret).Error
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -1108,16 +1905,17 @@ func (base *CacheDaoBase) sqlGetById(id uint64) (interface{}, error) {
 	return ret, nil
 }
 
-func (base *CacheDaoBase) sqlGetByIds(ids []uint64) (interface{}, error) {
+func (base *CacheDaoBase) sqlGetByKeys(ctx context.Context, keys []interface{}) (interface{}, error) {
+	base.stats(metricsMethodGetByIds).dbFallback(len(keys))
 	doType := reflect.TypeOf(base.Do)
 	if doType.Kind() == reflect.Ptr {
 		doType = doType.Elem()
 	}
 	model := base.makeObjInstancePtr()
-	db := base.ReadDBSource.Model(model)
+	db := base.ReadDBSource.WithContext(ctx).Table(base.schema.Table).Model(model)
 
 	ret := base.makeObjListPtr()
-	err := db.Where("id in ?", ids).Find(ret).Error
+	err := db.Where(base.primaryKeysCondition(keys)).Find(ret).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,314 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/gorm"
+)
+
+func TestNegativeCache(t *testing.T) {
+	db := newTestDB(t)
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.NegativeExpireTime = 60
+	})
+	user := &testUser{Id: 42, Name: "alice", Status: 1}
+
+	// miss: absent row and empty range are cached
+	if obj, err := dao.GetById(user.Id); err != nil || obj != nil {
+		t.Fatalf("GetById absent: got %v %v", obj, err)
+	}
+	if got, err := dao.GetByName(user.Name); err != nil || got != nil {
+		t.Fatalf("GetByName absent: got %v %v", got, err)
+	}
+	if list, err := dao.GetByStatus(db, user.Status); err != nil || len(list) != 0 {
+		t.Fatalf("GetByStatus empty: got %v %v", list, err)
+	}
+
+	// create without notify: the sentinels are still served
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if obj, err := dao.GetById(user.Id); err != nil || obj != nil {
+		t.Fatalf("GetById cached as absent: got %v %v", obj, err)
+	}
+	if got, err := dao.GetByName(user.Name); err != nil || got != nil {
+		t.Fatalf("GetByName cached as absent: got %v %v", got, err)
+	}
+
+	// hit after notified
+	if err := dao.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+	if obj, err := dao.GetById(user.Id); err != nil || obj == nil || obj.(*testUser).Name != user.Name {
+		t.Fatalf("GetById after created: got %v %v", obj, err)
+	}
+	if got, err := dao.GetByName(user.Name); err != nil || got == nil || got.Id != user.Id {
+		t.Fatalf("GetByName after created: got %v %v", got, err)
+	}
+	if list, err := dao.GetByStatus(db, user.Status); err != nil || len(list) != 1 || list[0].Id != user.Id {
+		t.Fatalf("GetByStatus after created: got %v %v", list, err)
+	}
+}
+
+func TestUpdateVersionMonotonic(t *testing.T) {
+	dao := newTestCacheDao(t, newTestDB(t), nil)
+	ctx := context.Background()
+	key := "V_test_monotonic"
+	version := func() int64 {
+		t.Helper()
+		item, err := dao.cacheStore().Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return util.ConvertStringToNumber(string(item.Value))
+	}
+
+	// added if absent
+	if err := dao.updateVersion(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(); v < time.Now().Add(-time.Minute).UnixNano()/1e6 {
+		t.Fatalf("version added: got %d, want about now", v)
+	}
+
+	// the version not earlier than now is bumped by 1, so it changes even in the same millisecond
+	future := time.Now().Add(time.Hour).UnixNano() / 1e6
+	dao.cacheStore().Set(ctx, &Item{Key: key, Value: []byte(util.ConvertNumberToString(future))})
+	for i := int64(1); i <= 3; i++ {
+		if err := dao.updateVersion(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		if v := version(); v != future+i {
+			t.Fatalf("version bumped: got %d, want %d", v, future+i)
+		}
+	}
+
+	// concurrent bumps are not lost
+	start := version()
+	n := 8
+	wg := sync.WaitGroup{}
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- dao.updateVersion(ctx, key)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := version(); v != start+int64(n) {
+		t.Fatalf("version after %d concurrent bumps: got %d, want %d", n, v, start+int64(n))
+	}
+}
+
+// conflictingStore memory store whose CAS always conflicts
+type conflictingStore struct {
+	*memStore
+}
+
+func (s *conflictingStore) CAS(ctx context.Context, item *Item) error {
+	return ErrCASConflict
+}
+
+// failingStore memory store which fails all operations but sets
+type failingStore struct {
+	*memStore
+}
+
+func (s *failingStore) Get(ctx context.Context, key string) (*Item, error) {
+	return nil, errors.New("store is down")
+}
+
+func (s *failingStore) Add(ctx context.Context, item *Item) error {
+	return errors.New("store is down")
+}
+
+func TestNotifyModifiedError(t *testing.T) {
+	user := &testUser{Id: 1, Name: "alice", Status: 1}
+
+	logger := &recordLogger{}
+	dao := newTestCacheDao(t, newTestDB(t), func(dao *testUserCacheDao) {
+		dao.Store = &failingStore{memStore: newMemStore()}
+		dao.Logger = logger
+	})
+	for name, err := range map[string]error{
+		"NotifyModified":   dao.NotifyModified(user),
+		"NotifyUpdated":    dao.NotifyUpdated(user, &testUser{Id: 1, Name: "bob", Status: 1}),
+		"NotifyModifiedTx": dao.NotifyModifiedTx(nil, user),
+	} {
+		if err == nil || !strings.Contains(err.Error(), "store is down") {
+			t.Fatalf("%s with store down: got %v", name, err)
+		}
+	}
+	if !logger.logged("update version failed") {
+		t.Fatal("failure of update version is not logged")
+	}
+
+	// version conflicts more than the retries
+	store := &conflictingStore{memStore: newMemStore()}
+	dao = newTestCacheDao(t, newTestDB(t), func(dao *testUserCacheDao) {
+		dao.Store = store
+		dao.Logger = logger
+	})
+	err := dao.NotifyModified(user)
+	if err != nil {
+		t.Fatalf("NotifyModified of absent versions: %v", err)
+	}
+	err = dao.NotifyModified(user)
+	if err == nil || !strings.Contains(err.Error(), "modified by others") {
+		t.Fatalf("NotifyModified with versions conflicted: got %v", err)
+	}
+}
+
+func (d *testUserSQLDao) FindByName(name string) (*testUser, error) {
+	user := &testUser{}
+	err := d.db.Where("name = ?", name).First(user).Error
+	return user, err
+}
+
+func (d *testUserSQLDao) FindByNameInMissingTable(name string) (*testUser, error) {
+	user := &testUser{}
+	err := d.db.Table("missing_users").Where("name = ?", name).First(user).Error
+	return user, err
+}
+
+func TestSQLDaoResult(t *testing.T) {
+	boom := errors.New("boom")
+	user := &testUser{Name: "alice"}
+	cases := []struct {
+		name    string
+		retVals []interface{}
+		want    interface{}
+		wantErr error
+	}{
+		{"result only", []interface{}{user}, user, nil},
+		{"nil error", []interface{}{user, nil}, user, nil},
+		{"error", []interface{}{user, boom}, nil, boom},
+		{"not found", []interface{}{user, gorm.ErrRecordNotFound}, nil, nil},
+		{"wrapped not found", []interface{}{user, fmt.Errorf("find: %w", gorm.ErrRecordNotFound)}, nil, nil},
+	}
+	for _, c := range cases {
+		ret, err := sqlDaoResult(c.retVals)
+		if !errors.Is(err, c.wantErr) || (c.wantErr == nil && err != nil) {
+			t.Fatalf("%s: got error %v, want %v", c.name, err, c.wantErr)
+		}
+		if ret != c.want {
+			t.Fatalf("%s: got %v, want %v", c.name, ret, c.want)
+		}
+	}
+	if _, err := sqlDaoResult(nil); err == nil {
+		t.Fatal("no return value: error expected")
+	}
+}
+
+func TestSQLDaoErrors(t *testing.T) {
+	db := newTestDB(t)
+	var find, broken, brokenList *CachedMethod
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		find = dao.MustBindMethod(&MethodBinding{Method: (*testUserSQLDao).FindByName, Type: constant.NotifyTypeConcrete, Keys: []string{"Name"}, Args: []int{0}})
+		broken = dao.MustBindMethod(&MethodBinding{Method: (*testUserSQLDao).FindByNameInMissingTable, Type: constant.NotifyTypeConcrete, Keys: []string{"Name"}, Args: []int{0}})
+		brokenList = dao.MustBindMethod(&MethodBinding{Method: (*testUserSQLDao).FindByNameInMissingTable, Type: constant.NotifyTypeMulti, Keys: []string{"Name"}, Args: []int{0}})
+	})
+
+	// ErrRecordNotFound is taken as absent
+	if user, err := ToObject[testUser](find.Get("alice")); err != nil || user != nil {
+		t.Fatalf("FindByName absent: got %v %v", user, err)
+	}
+	alice := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(alice).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.NotifyModified(alice); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := ToObject[testUser](find.Get("alice")); err != nil || user == nil || user.Id != alice.Id {
+		t.Fatalf("FindByName: got %v %v", user, err)
+	}
+
+	// other errors reach the caller, and nothing is cached
+	for i := 0; i < 2; i++ {
+		if user, err := broken.Get("alice"); err == nil || !strings.Contains(err.Error(), "missing_users") || user != nil {
+			t.Fatalf("FindByNameInMissingTable: got %v %v", user, err)
+		}
+		if users, err := brokenList.Get("alice"); err == nil || !strings.Contains(err.Error(), "missing_users") || users != nil {
+			t.Fatalf("FindByNameInMissingTable of multi: got %v %v", users, err)
+		}
+	}
+}
+
+func TestMakeUpdateInvalidation(t *testing.T) {
+	dao := newTestCacheDao(t, newTestDB(t), nil)
+	before := &testUser{Id: 1, Name: "alice", Status: 1}
+	cases := []struct {
+		name  string
+		after *testUser
+		want  []string
+	}{
+		// range version key doesn't contain field values, it's not doubled
+		{"indexed field changed", &testUser{Id: 1, Name: "bob", Status: 2}, versionKeysOf(dao, "bob", "alice")},
+		{"indexed field unchanged", &testUser{Id: 1, Name: "alice", Status: 2}, versionKeysOf(dao, "alice")},
+	}
+	for _, c := range cases {
+		key, versionKeys := dao.makeUpdateInvalidation(before, c.after)
+		if key != uint64(1) {
+			t.Fatalf("%s: got key %v", c.name, key)
+		}
+		sort.Strings(versionKeys)
+		sort.Strings(c.want)
+		if strings.Join(versionKeys, ",") != strings.Join(c.want, ",") {
+			t.Fatalf("%s: got version keys %v, want %v", c.name, versionKeys, c.want)
+		}
+	}
+}
+
+func TestNotifyUpdated(t *testing.T) {
+	db := newTestDB(t)
+	dao := newTestCacheDao(t, db, nil)
+	alice, _ := createTestUsers(t, db)
+	for _, byCache := range []bool{false, true} {
+		oldName, newName := alice.Name, alice.Name+"_renamed"
+		if user, err := dao.GetByName(oldName); err != nil || user == nil {
+			t.Fatalf("GetByName %s: got %v %v", oldName, user, err)
+		}
+		if user, err := dao.GetByName(newName); err != nil || user != nil {
+			t.Fatalf("GetByName %s: got %v %v", newName, user, err)
+		}
+
+		// the cached object is taken as the old row if before is absent
+		cacheTestObject(t, dao, alice.Id)
+		before := *alice
+		alice.Name = newName
+		if err := db.Save(alice).Error; err != nil {
+			t.Fatal(err)
+		}
+		checkBumped(t, dao, versionKeysOf(dao, oldName, newName), func() error {
+			if byCache {
+				return dao.NotifyUpdated(nil, alice)
+			}
+			return dao.NotifyUpdated(&before, alice)
+		})
+
+		// neither the old nor the new name is stale
+		if user, err := dao.GetByName(oldName); err != nil || user != nil {
+			t.Fatalf("GetByName %s after update: got %v %v", oldName, user, err)
+		}
+		if user, err := dao.GetByName(newName); err != nil || user == nil || user.Id != alice.Id {
+			t.Fatalf("GetByName %s after update: got %v %v", newName, user, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/util"
+)
+
+// MethodBinding explicit declaration of a cached sql dao method, alternative of the 'meta' field notify tag
+type MethodBinding struct {
+	Method interface{} // sql dao method name, or func value like `(*UserSQLDao).GetByName` and `sqlDao.GetByName`
+	Type   string      // refer: constant
+	Keys   []string    // do fields that construct the cache key
+	Args   []int       // method arg indexs that mapped to keys
+}
+
+// CachedMethod handle of cached sql dao method, invoking through it needs no runtime stack lookup
+type CachedMethod struct {
+	Name string // sql dao method name
+	Type string
+	Keys []string
+	Args []int
+
+	base *CacheDaoBase
+}
+
+// BindMethod declare cached sql dao method explicitly, it should be invoked before 'Initialize',
+// as the notify infos are not modified after that.
+func (base *CacheDaoBase) BindMethod(binding *MethodBinding) (*CachedMethod, error) {
+	if binding == nil {
+		return nil, errors.New("method binding should not be nil")
+	}
+	methodName, err := resolveMethodName(binding.Method)
+	if err != nil {
+		return nil, err
+	}
+	if base.MethodNotifyInfoMap != nil {
+		return nil, fmt.Errorf("method '%s' should be bound before 'Initialize'", methodName)
+	}
+	if base.SQLDao == nil {
+		return nil, fmt.Errorf("sql dao has no method '%s'", methodName)
+	}
+	err = base.checkNotifyError(methodName, binding.Type, binding.Keys, binding.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	method := &CachedMethod{
+		Name: methodName,
+		Type: binding.Type,
+		Keys: binding.Keys,
+		Args: binding.Args,
+		base: base,
+	}
+	base.boundMethods = append(base.boundMethods, method)
+	return method, nil
+}
+
+// MustBindMethod like 'BindMethod', but panic if failed
+func (base *CacheDaoBase) MustBindMethod(binding *MethodBinding) *CachedMethod {
+	method, err := base.BindMethod(binding)
+	if err != nil {
+		panic(err)
+	}
+	return method
+}
+
+// Get invoke cached method by its notify type, 'concrete' returns single object, 'list', 'multi' and 'range' return list,
+// 'aggregate' returns the first return value of method
+func (m *CachedMethod) Get(args ...interface{}) (interface{}, error) {
+	return m.GetContext(context.Background(), args...)
+}
+
+// GetContext invoke cached method by its notify type, with context
+func (m *CachedMethod) GetContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	switch m.Type {
+	case constant.NotifyTypeConcrete:
+		return m.base.getByConcreteKey(ctx, m.Name, args...)
+	case constant.NotifyTypeList:
+		return m.base.getByConcreteKeys(ctx, m.Name, args...)
+	case constant.NotifyTypeRange:
+		return m.base.getByRange(ctx, m.Name, args...)
+	case constant.NotifyTypeMulti:
+		return m.base.getAllByConcreteKey(ctx, m.Name, args...)
+	case constant.NotifyTypeAggregate:
+		return m.base.getByAggregate(ctx, m.Name, args...)
+	}
+	return nil, fmt.Errorf("unsupported notify type '%s' of method '%s'", m.Type, m.Name)
+}
+
+// GetPage invoke cached 'range' method by 'GetByRangePage', return the page of [offset, offset+limit) and the total count
+func (m *CachedMethod) GetPage(offset, limit int, args ...interface{}) (interface{}, int64, error) {
+	return m.GetPageContext(context.Background(), offset, limit, args...)
+}
+
+// GetPageContext invoke cached 'range' method by 'GetByRangePage', with context
+func (m *CachedMethod) GetPageContext(ctx context.Context, offset, limit int, args ...interface{}) (interface{}, int64, error) {
+	if m.Type != constant.NotifyTypeRange {
+		return nil, 0, fmt.Errorf("method '%s' of notify type '%s' can't be paged", m.Name, m.Type)
+	}
+	return m.base.getByRangePage(ctx, m.Name, offset, limit, args...)
+}
+
+// registerNotifyInfo register notify info of sql dao method
+func (base *CacheDaoBase) registerNotifyInfo(methodName string, notifyType string, keys []string, args []int) {
+	doType := util.GetPointToType(reflect.TypeOf(base.Do))
+
+	// FIXME: if sort `keys`, the duplicated notifyInfo can be more less.
+	versionKeyPrefix := "V_" + doType.Name() + "_" + strings.Join(keys, "_")
+	notifyInfo := &NotifyInfo{
+		Type:             notifyType,
+		Fields:           keys,
+		Args:             args,
+		VersionKeyPrefix: versionKeyPrefix,
+	}
+
+	// make notiyInfo array, range version key is made of field names rather than values,
+	// so it doesn't duplicate the others of same keys
+	exist := false
+	for _, info := range base.NotifyInfos {
+		if info.VersionKeyPrefix == versionKeyPrefix && (info.Type == constant.NotifyTypeRange) == (notifyType == constant.NotifyTypeRange) {
+			exist = true
+			break
+		}
+	}
+	if !exist {
+		base.NotifyInfos = append(base.NotifyInfos, notifyInfo)
+	}
+
+	// make method notify map
+	base.MethodNotifyInfoMap[methodName] = notifyInfo
+}
+
+// resolveMethodName get method name from name string or func value
+func resolveMethodName(method interface{}) (string, error) {
+	switch m := method.(type) {
+	case string:
+		if m == "" {
+			return "", errors.New("method name should not be empty")
+		}
+		return m, nil
+	case nil:
+		return "", errors.New("method should not be nil")
+	}
+
+	methodValue := reflect.ValueOf(method)
+	if methodValue.Kind() != reflect.Func {
+		return "", fmt.Errorf("method should be name or func, but got %v", methodValue.Type())
+	}
+	fullName := runtime.FuncForPC(methodValue.Pointer()).Name()
+	// method value is compiled to a closure named with '-fm' suffix
+	fullName = strings.TrimSuffix(fullName, "-fm")
+	parts := strings.Split(fullName, ".")
+	return parts[len(parts)-1], nil
+}
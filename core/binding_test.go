@@ -0,0 +1,38 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/constant"
+)
+
+func TestBindMethodBeforeInitialize(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&testUser{Name: "alice", Status: 1})
+	binding := &MethodBinding{
+		Method: (*testUserSQLDao).GetByName,
+		Type:   constant.NotifyTypeConcrete,
+		Keys:   []string{"Name"},
+		Args:   []int{0},
+	}
+
+	var method *CachedMethod
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		method = dao.MustBindMethod(binding)
+	})
+	result, err := method.Get("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ToObject[testUser](result, nil)
+	if err != nil || user == nil || user.Name != "alice" {
+		t.Fatalf("Get of bound method: got %v %v", user, err)
+	}
+
+	// notify infos are not modified after initialize
+	_, err = dao.BindMethod(binding)
+	if err == nil || !strings.Contains(err.Error(), "before 'Initialize'") {
+		t.Fatalf("BindMethod after initialize: got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/zhyeah/gorm-cache/constant"
+)
+
+// default size in bytes above which values are compressed
+const defaultCompressThreshold = 1024
+
+// magic prefix of the value serialized by CompressSerializer, followed by the header byte.
+// no value serialized by json, msgpack, gob or protobuf starts with it: 0x00 is not a valid first byte of json,
+// nor a valid field tag of protobuf or message length of gob, and it's a whole value (int 0) of msgpack,
+// so the values written by the wrapped serializer directly are still readable.
+const compressMagic = "\x00GC"
+
+// header byte of the value serialized by CompressSerializer, tells how the payload is compressed
+const (
+	compressHeaderNone   byte = 0x00
+	compressHeaderSnappy byte = 0x01
+	compressHeaderZstd   byte = 0x02
+	compressHeaderGzip   byte = 0x03
+)
+
+// CompressSerializer wrap a serializer, compress the serialized value larger than 'Threshold'.
+// each value is prefixed with a magic and a header byte, so compressed and uncompressed values (or of other algorithm) decode correctly.
+// it helps to keep large objects (like those with TEXT columns) under the item size limit of cache store (1MB of memcache).
+type CompressSerializer struct {
+	Serializer Serializer // the wrapped serializer, json if nil
+	Algorithm  string     // refer: constant, default snappy
+	Threshold  int        // values not larger than it are stored uncompressed, default 1024, compress all if < 0
+}
+
+// Serialize serialize obj, compress it if it's larger than threshold
+func (s *CompressSerializer) Serialize(obj interface{}) ([]byte, error) {
+	data, err := s.serializer().Serialize(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= s.threshold() {
+		return append(compressPrefix(compressHeaderNone), data...), nil
+	}
+
+	switch s.Algorithm {
+	case "", constant.CompressionSnappy:
+		prefix := compressPrefix(compressHeaderSnappy)
+		dst := make([]byte, len(prefix)+snappy.MaxEncodedLen(len(data)))
+		copy(dst, prefix)
+		return dst[:len(prefix)+len(snappy.Encode(dst[len(prefix):], data))], nil
+	case constant.CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, compressPrefix(compressHeaderZstd)), nil
+	case constant.CompressionGzip:
+		buf := bytes.NewBuffer(compressPrefix(compressHeaderGzip))
+		writer := gzip.NewWriter(buf)
+		_, err = writer.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown compression algorithm '%s'", s.Algorithm)
+}
+
+// Deserialize decompress the value by its header, then deserialize it.
+// the value without magic (written by the wrapped serializer directly) is taken as it is.
+func (s *CompressSerializer) Deserialize(bts []byte, obj interface{}) error {
+	if len(bts) <= len(compressMagic) || string(bts[:len(compressMagic)]) != compressMagic {
+		return s.serializer().Deserialize(bts, obj)
+	}
+
+	header := bts[len(compressMagic)]
+	data := bts[len(compressMagic)+1:]
+	var err error
+	switch header {
+	case compressHeaderNone:
+	case compressHeaderSnappy:
+		data, err = snappy.Decode(nil, data)
+	case compressHeaderZstd:
+		var decoder *zstd.Decoder
+		decoder, err = zstdDecoder()
+		if err == nil {
+			data, err = decoder.DecodeAll(data, nil)
+		}
+	case compressHeaderGzip:
+		var reader *gzip.Reader
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+		if err == nil {
+			data, err = ioutil.ReadAll(reader)
+		}
+	default:
+		err = fmt.Errorf("unknown compression header 0x%02x", header)
+	}
+	if err != nil {
+		return fmt.Errorf("decompress cached value failed: %w", err)
+	}
+	return s.serializer().Deserialize(data, obj)
+}
+
+// compressPrefix magic followed by header
+func compressPrefix(header byte) []byte {
+	return append([]byte(compressMagic), header)
+}
+
+func (s *CompressSerializer) serializer() Serializer {
+	if s.Serializer != nil {
+		return s.Serializer
+	}
+	return &JSONSerializer{}
+}
+
+func (s *CompressSerializer) threshold() int {
+	if s.Threshold == 0 {
+		return defaultCompressThreshold
+	}
+	return s.Threshold
+}
+
+// zstd encoder and decoder are safe for concurrent EncodeAll/DecodeAll, shared by all serializers
+var (
+	zstdOnce    sync.Once
+	zstdEnc     *zstd.Encoder
+	zstdDec     *zstd.Decoder
+	zstdInitErr error
+)
+
+func initZstd() {
+	zstdEnc, zstdInitErr = zstd.NewWriter(nil)
+	if zstdInitErr != nil {
+		return
+	}
+	zstdDec, zstdInitErr = zstd.NewReader(nil)
+}
+
+func zstdEncoder() (*zstd.Encoder, error) {
+	zstdOnce.Do(initZstd)
+	return zstdEnc, zstdInitErr
+}
+
+func zstdDecoder() (*zstd.Decoder, error) {
+	zstdOnce.Do(initZstd)
+	return zstdDec, zstdInitErr
+}
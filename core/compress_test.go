@@ -0,0 +1,98 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCompressSerializerHeaders(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		threshold int
+		header    byte
+	}{
+		{constant.CompressionSnappy, 1 << 20, compressHeaderNone},
+		{"", -1, compressHeaderSnappy},
+		{constant.CompressionSnappy, 0, compressHeaderSnappy},
+		{constant.CompressionZstd, 0, compressHeaderZstd},
+		{constant.CompressionGzip, 0, compressHeaderGzip},
+	}
+	inners := []Serializer{nil, &MsgpackSerializer{}, &GobSerializer{}}
+	for _, c := range cases {
+		for _, inner := range inners {
+			s := &CompressSerializer{Serializer: inner, Algorithm: c.algorithm, Threshold: c.threshold}
+			data, err := s.Serialize(newBenchUser(4096))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), compressMagic) || data[len(compressMagic)] != c.header {
+				t.Fatalf("header of %q by %T: got % x", c.algorithm, inner, data[:len(compressMagic)+1])
+			}
+			checkRoundTrip(t, s, newBenchUser(4096))
+			checkRoundTrip(t, s, &[]benchOrder{*newBenchOrder(2048), *newBenchOrder(16)})
+		}
+	}
+
+	s := &CompressSerializer{Algorithm: "lz4", Threshold: -1}
+	if _, err := s.Serialize(newBenchUser(16)); err == nil {
+		t.Fatal("Serialize with unknown algorithm should fail")
+	}
+	err := s.Deserialize([]byte(compressMagic+"\x09{}"), &benchUser{})
+	if err == nil || !strings.Contains(err.Error(), "unknown compression header") {
+		t.Fatalf("Deserialize with unknown header: got %v", err)
+	}
+}
+
+func TestCompressSerializerLegacy(t *testing.T) {
+	// values written by the wrapped serializer directly, before compression is enabled
+	for _, inner := range []Serializer{&JSONSerializer{}, &MsgpackSerializer{}, &GobSerializer{}} {
+		s := &CompressSerializer{Serializer: inner}
+		checkLegacy(t, s, newBenchUser(64))
+		checkLegacy(t, s, &[]benchOrder{*newBenchOrder(8)})
+	}
+
+	// msgpack encodes ints 0 - 3 as the bytes of the headers (positive fixint)
+	s := &CompressSerializer{Serializer: &MsgpackSerializer{}}
+	for i := int64(0); i <= 3; i++ {
+		var got int64 = -1
+		err := s.Deserialize([]byte{byte(i)}, &got)
+		if err != nil || got != i {
+			t.Fatalf("legacy msgpack value %d: got %d %v", i, got, err)
+		}
+	}
+
+	s = &CompressSerializer{Serializer: &ProtobufSerializer{}}
+	ts := timestamppb.New(time.Unix(1600000000, 3))
+	data, err := proto.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &timestamppb.Timestamp{}
+	err = s.Deserialize(data, decoded)
+	if err != nil || !proto.Equal(ts, decoded) {
+		t.Fatalf("legacy protobuf value: got %v %v", decoded, err)
+	}
+}
+
+// checkLegacy check that the value serialized by the wrapped serializer of s directly is deserialized by s
+func checkLegacy(t *testing.T, s *CompressSerializer, objPtr interface{}) {
+	t.Helper()
+	data, err := s.Serializer.Serialize(objPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := reflect.New(reflect.TypeOf(objPtr).Elem())
+	err = s.Deserialize(data, decoded.Interface())
+	if err != nil {
+		t.Fatalf("legacy value of %T: %v", s.Serializer, err)
+	}
+	if !equalValues(reflect.ValueOf(objPtr).Elem(), decoded.Elem()) {
+		t.Fatalf("legacy value of %T: got %+v", s.Serializer, decoded.Elem().Interface())
+	}
+}
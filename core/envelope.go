@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// envelopeMagic first byte of enveloped object cache, followed by the schema fingerprint and the serialized object
+const envelopeMagic byte = 0xFE
+
+// bytes of schema fingerprint kept in envelope
+const fingerprintSize = 8
+
+// ErrSchemaMismatch means that the object cache was written for another schema of Do (or without envelope),
+// it's taken as cache miss, and the object is loaded from sql again.
+var ErrSchemaMismatch = errors.New("gormcache: schema fingerprint mismatch")
+
+// schemaFingerprint fingerprint of the exported fields of Do, with their types and tags,
+// it changes when a field is added, removed, renamed or retyped, recursively in embedded and nested structs.
+func schemaFingerprint(doType reflect.Type) []byte {
+	sb := &strings.Builder{}
+	describeType(sb, doType, make(map[reflect.Type]bool))
+	sum := sha256.Sum256([]byte(sb.String()))
+	return sum[:fingerprintSize]
+}
+
+func describeType(sb *strings.Builder, t reflect.Type, visited map[reflect.Type]bool) {
+	sb.WriteString(t.String())
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		sb.WriteString("<")
+		if t.Kind() == reflect.Map {
+			describeType(sb, t.Key(), visited)
+			sb.WriteString(",")
+		}
+		describeType(sb, t.Elem(), visited)
+		sb.WriteString(">")
+	case reflect.Struct:
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		sb.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				// unexported fields are not serialized
+				continue
+			}
+			sb.WriteString(field.Name)
+			sb.WriteString(" ")
+			describeType(sb, field.Type, visited)
+			sb.WriteString(" `")
+			sb.WriteString(string(field.Tag))
+			sb.WriteString("`;")
+		}
+		sb.WriteString("}")
+	}
+}
+
+// serializeObject serialize object for object cache, in envelope if 'SchemaEnvelope' is set
+func (base *CacheDaoBase) serializeObject(obj interface{}) ([]byte, error) {
+	data, err := base.Serializer.Serialize(obj)
+	if err != nil || !base.SchemaEnvelope {
+		return data, err
+	}
+	enveloped := make([]byte, 0, 1+fingerprintSize+len(data))
+	enveloped = append(enveloped, envelopeMagic)
+	enveloped = append(enveloped, base.schemaFingerprint...)
+	return append(enveloped, data...), nil
+}
+
+// deserializeObject deserialize object cache, return ErrSchemaMismatch if the envelope is absent or of another schema
+func (base *CacheDaoBase) deserializeObject(data []byte, objPtr interface{}) error {
+	if base.SchemaEnvelope {
+		if len(data) < 1+fingerprintSize || data[0] != envelopeMagic || !bytes.Equal(data[1:1+fingerprintSize], base.schemaFingerprint) {
+			return ErrSchemaMismatch
+		}
+		data = data[1+fingerprintSize:]
+	}
+	return base.Serializer.Deserialize(data, objPtr)
+}
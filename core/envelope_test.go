@@ -0,0 +1,198 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaFingerprint(t *testing.T) {
+	// the local types have the same name, as the Do before and after a deploy
+	base := func() reflect.Type {
+		type user struct {
+			Id   uint64
+			Name string
+		}
+		return reflect.TypeOf(user{})
+	}()
+	same := func() reflect.Type {
+		type user struct {
+			Id   uint64
+			Name string
+			note string
+		}
+		return reflect.TypeOf(user{})
+	}()
+	changed := map[string]reflect.Type{
+		"added": func() reflect.Type {
+			type user struct {
+				Id     uint64
+				Name   string
+				Status int
+			}
+			return reflect.TypeOf(user{})
+		}(),
+		"renamed": func() reflect.Type {
+			type user struct {
+				Id       uint64
+				Nickname string
+			}
+			return reflect.TypeOf(user{})
+		}(),
+		"retyped": func() reflect.Type {
+			type user struct {
+				Id   uint64
+				Name []byte
+			}
+			return reflect.TypeOf(user{})
+		}(),
+		"tagged": func() reflect.Type {
+			type user struct {
+				Id   uint64
+				Name string `json:"name"`
+			}
+			return reflect.TypeOf(user{})
+		}(),
+		"nested": func() reflect.Type {
+			type profile struct{ Age int }
+			type user struct {
+				Id      uint64
+				Name    string
+				Profile *profile
+			}
+			return reflect.TypeOf(user{})
+		}(),
+	}
+
+	fingerprint := schemaFingerprint(base)
+	if len(fingerprint) != fingerprintSize || !bytes.Equal(fingerprint, schemaFingerprint(base)) {
+		t.Fatalf("fingerprint should be stable with %d bytes, got %x", fingerprintSize, fingerprint)
+	}
+	// unexported fields are not serialized
+	if !bytes.Equal(fingerprint, schemaFingerprint(same)) {
+		t.Fatal("fingerprint should ignore unexported fields")
+	}
+	for name, doType := range changed {
+		if bytes.Equal(fingerprint, schemaFingerprint(doType)) {
+			t.Fatalf("fingerprint should change when field is %s", name)
+		}
+	}
+
+	nestedBase := changed["nested"]
+	nestedChanged := func() reflect.Type {
+		type profile struct{ Age string }
+		type user struct {
+			Id      uint64
+			Name    string
+			Profile *profile
+		}
+		return reflect.TypeOf(user{})
+	}()
+	if bytes.Equal(schemaFingerprint(nestedBase), schemaFingerprint(nestedChanged)) {
+		t.Fatal("fingerprint should change when nested struct changes")
+	}
+}
+
+func TestSchemaEnvelope(t *testing.T) {
+	db := newTestDB(t)
+	alice, bob := createTestUsers(t, db)
+	ctx := context.Background()
+	for _, enabled := range []bool{true, false} {
+		dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+			dao.SchemaEnvelope = enabled
+		})
+		store := dao.Store.(*memStore)
+		getName := func() (string, error) {
+			user, err := ToObject[testUser](dao.GetById(alice.Id))
+			if err != nil {
+				return "", err
+			}
+			return user.Name, nil
+		}
+		getNames := func() string {
+			users, err := ToList[testUser](dao.GetByIds([]uint64{alice.Id, bob.Id}))
+			if err != nil || len(users) != 2 {
+				t.Fatalf("GetByIds: got %v %v", users, err)
+			}
+			return users[0].Name + "," + users[1].Name
+		}
+		if name, err := getName(); err != nil || name != "alice" {
+			t.Fatalf("GetById: got %s %v", name, err)
+		}
+		legacy, _ := json.Marshal(&testUser{Id: alice.Id, Name: "legacy", Status: 1})
+		// the object key changes with the version when the object cache is repopulated
+		objectKey := func() string {
+			key, err := dao.GetObjectKey(alice.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return key
+		}
+		current := func() []byte {
+			item, err := store.Get(ctx, objectKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return item.Value
+		}
+		set := func(value []byte) {
+			if err := store.Set(ctx, &Item{Key: objectKey(), Value: value}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if !enabled {
+			// cached as serialized, and the values without envelope are served as they are
+			if !bytes.Equal(current(), mustSerialize(t, dao, alice)) {
+				t.Fatalf("object cache without envelope: got %q", current())
+			}
+			set(legacy)
+			if name, err := getName(); err != nil || name != "legacy" {
+				t.Fatalf("GetById of legacy value without envelope: got %s %v", name, err)
+			}
+			set(envelope(dao.schemaFingerprint, mustSerialize(t, dao, alice)))
+			if _, err := getName(); err == nil {
+				t.Fatal("GetById of enveloped value should fail without envelope")
+			}
+			continue
+		}
+
+		enveloped := envelope(dao.schemaFingerprint, mustSerialize(t, dao, alice))
+		if !bytes.Equal(current(), enveloped) {
+			t.Fatalf("object cache in envelope: got %q, want %q", current(), enveloped)
+		}
+
+		// values of another schema and legacy values are missed and repopulated
+		otherSchema := envelope(make([]byte, fingerprintSize), legacy)
+		for _, value := range [][]byte{otherSchema, legacy, {envelopeMagic}} {
+			set(value)
+			if name, err := getName(); err != nil || name != "alice" {
+				t.Fatalf("GetById of %q: got %s %v", value, name, err)
+			}
+			if !bytes.Equal(current(), enveloped) {
+				t.Fatalf("object cache of %q should be repopulated, got %q", value, current())
+			}
+			set(value)
+			if names := getNames(); names != "alice,bob" {
+				t.Fatalf("GetByIds of %q: got %s", value, names)
+			}
+		}
+	}
+}
+
+func mustSerialize(t *testing.T, dao *testUserCacheDao, obj interface{}) []byte {
+	t.Helper()
+	data, err := dao.Serializer.Serialize(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// envelope wrap data in envelope of fingerprint
+func envelope(fingerprint []byte, data []byte) []byte {
+	enveloped := append([]byte{envelopeMagic}, fingerprint...)
+	return append(enveloped, data...)
+}
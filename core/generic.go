@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/zhyeah/gorm-cache/util"
+)
+
+// CacheDao type-safe wrapper of CacheDaoBase, T is the database object model ('Do')
+type CacheDao[T any] struct {
+	Base *CacheDaoBase
+}
+
+// NewCacheDao new type-safe cache dao on the base, T should be the type of 'Do' of the base
+func NewCacheDao[T any](base *CacheDaoBase) (*CacheDao[T], error) {
+	if base == nil || base.Do == nil {
+		return nil, errors.New("cache dao base and its 'Do' should not be nil")
+	}
+	doType := util.GetPointToType(reflect.TypeOf(base.Do))
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t != doType {
+		return nil, fmt.Errorf("type '%v' mismatches 'Do' type '%v' of cache dao", t, doType)
+	}
+	return &CacheDao[T]{Base: base}, nil
+}
+
+// GetById try get from cache first, if absent, load it from sql. return nil if not found.
+func (dao *CacheDao[T]) GetById(ctx context.Context, id uint64) (*T, error) {
+	return ToObject[T](dao.Base.GetByIdContext(ctx, id))
+}
+
+// GetByIds try to get from cache first, if absent, load them from sql. result keeps the order of ids.
+func (dao *CacheDao[T]) GetByIds(ctx context.Context, ids []uint64) ([]T, error) {
+	return ToList[T](dao.Base.GetByIdsContext(ctx, ids))
+}
+
+// GetByPrimaryKey like 'GetById', for any type of primary key, use CompositeKey for composite primary key.
+func (dao *CacheDao[T]) GetByPrimaryKey(ctx context.Context, key interface{}) (*T, error) {
+	return ToObject[T](dao.Base.GetByPrimaryKeyContext(ctx, key))
+}
+
+// GetByPrimaryKeys like 'GetByIds', keys is slice of any type of primary key. result keeps the order of keys.
+func (dao *CacheDao[T]) GetByPrimaryKeys(ctx context.Context, keys interface{}) ([]T, error) {
+	return ToList[T](dao.Base.GetByPrimaryKeysContext(ctx, keys))
+}
+
+// NotifyModified when do action like add/edit/delete, invoke this to update cache
+func (dao *CacheDao[T]) NotifyModified(ctx context.Context, do *T) error {
+	if do == nil {
+		return nil
+	}
+	return dao.Base.NotifyModifiedContext(ctx, do)
+}
+
+// ToObject convert the result of untyped single object method (like 'GetByConcreteKey') to *T,
+// wrap the call directly, e.g. `return core.ToObject[User](dao.GetByConcreteKey(name))`.
+func ToObject[T any](ret interface{}, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, nil
+	}
+	switch v := ret.(type) {
+	case *T:
+		return v, nil
+	case T:
+		return &v, nil
+	}
+	return nil, fmt.Errorf("unexpected result type %v, want %v", reflect.TypeOf(ret), reflect.TypeOf((*T)(nil)))
+}
+
+// ToList convert the result of untyped list method (like 'GetByRange') to []T,
+// wrap the call directly, e.g. `return core.ToList[User](dao.GetByRange(db, status))`.
+func ToList[T any](ret interface{}, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return []T{}, nil
+	}
+	switch v := ret.(type) {
+	case *[]T:
+		if v == nil {
+			return []T{}, nil
+		}
+		return *v, nil
+	case []T:
+		return v, nil
+	case []*T:
+		list := make([]T, 0, len(v))
+		for i := range v {
+			if v[i] != nil {
+				list = append(list, *v[i])
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unexpected result type %v, want %v", reflect.TypeOf(ret), reflect.TypeOf([]T(nil)))
+}
+
+// ToPage convert the result of untyped page method (like 'GetByRangePage') to []T with total count,
+// wrap the call directly, e.g. `return core.ToPage[User](dao.GetByRangePage(offset, limit, db, status))`.
+func ToPage[T any](ret interface{}, total int64, err error) ([]T, int64, error) {
+	list, err := ToList[T](ret, err)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ToValue convert the result of untyped aggregate method (like 'GetByAggregate') to T,
+// wrap the call directly, e.g. `return core.ToValue[int64](dao.GetByAggregate(status))`.
+func ToValue[T any](ret interface{}, err error) (T, error) {
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+	if ret == nil {
+		return zero, nil
+	}
+	if v, ok := ret.(T); ok {
+		return v, nil
+	}
+	return zero, fmt.Errorf("unexpected result type %v, want %v", reflect.TypeOf(ret), reflect.TypeOf((*T)(nil)).Elem())
+}
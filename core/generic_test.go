@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewCacheDao(t *testing.T) {
+	db := newTestDB(t)
+	user := &testUser{Name: "alice", Status: 1}
+	db.Create(user)
+	base := &newTestCacheDao(t, db, nil).CacheDaoBase
+
+	dao, err := NewCacheDao[testUser](base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dao.GetById(context.Background(), user.Id)
+	if err != nil || got == nil || got.Name != "alice" {
+		t.Fatalf("GetById: got %v %v", got, err)
+	}
+
+	// type of T mismatches 'Do'
+	if _, err := NewCacheDao[testUserSQLDao](base); err == nil {
+		t.Fatal("NewCacheDao with mismatched type should fail")
+	}
+	if _, err := NewCacheDao[*testUser](base); err == nil {
+		t.Fatal("NewCacheDao with pointer type should fail")
+	}
+	if _, err := NewCacheDao[testUser](&CacheDaoBase{}); err == nil {
+		t.Fatal("NewCacheDao without 'Do' should fail")
+	}
+}
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
-	"github.com/zhyeah/gorm-cache/util"
+	"github.com/go-redis/redis/v8"
 )
 
 // CacheDaoMap 缓存dao map
@@ -17,17 +20,78 @@ type MemcacheConfig struct {
 	MaxIdleConns int
 }
 
+// RedisConfig redis缓存配置, multiple addrs means cluster (or sentinel if MasterName is set)
+type RedisConfig struct {
+	Addrs      []string
+	MasterName string
+	Password   string
+	DB         int
+	Timeout    int64
+	PoolSize   int
+}
+
 // MemcacheClient global memcache client
 var MemcacheClient *memcache.Client
 
-// InitializeCache initialize
-func InitializeCache(config *MemcacheConfig) {
+// RedisClient global redis client
+var RedisClient redis.UniversalClient
+
+// InitializeCache initialize with memcache as default store, return the errors of all cache daos failed to initialize
+func InitializeCache(config *MemcacheConfig) error {
 	MemcacheClient = memcache.New(config.Servers...)
 	MemcacheClient.Timeout = time.Duration(config.Timeout) * time.Millisecond
 	MemcacheClient.MaxIdleConns = config.MaxIdleConns
 
-	for _, v := range CacheDaoMap {
-		cdao := v()
-		util.ReflectInvokeMethod(cdao, "Initialize", cdao)
+	return InitializeCacheWithStore(NewMemcacheStore(MemcacheClient))
+}
+
+// InitializeRedisCache initialize with redis as default store, return the errors of all cache daos failed to initialize
+func InitializeRedisCache(config *RedisConfig) error {
+	timeout := time.Duration(config.Timeout) * time.Millisecond
+	RedisClient = redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:        config.Addrs,
+		MasterName:   config.MasterName,
+		Password:     config.Password,
+		DB:           config.DB,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		PoolSize:     config.PoolSize,
+	})
+
+	return InitializeCacheWithStore(NewRedisStore(RedisClient))
+}
+
+// cacheDaoInitializer cache dao registered in CacheDaoMap, which extends CacheDaoBase
+type cacheDaoInitializer interface {
+	Initialize(instance interface{}) error
+}
+
+// InitializeCacheWithStore initialize with specified default store,
+// all cache daos are initialized, and the errors of those failed are returned together.
+func InitializeCacheWithStore(store CacheStore) error {
+	DefaultStore = store
+
+	names := make([]string, 0, len(CacheDaoMap))
+	for name := range CacheDaoMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0)
+	for _, name := range names {
+		cdao, ok := CacheDaoMap[name]().(cacheDaoInitializer)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: cache dao should extend 'CacheDaoBase'", name))
+			continue
+		}
+		err := cdao.Initialize(cdao)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("initialize cache daos failed:\n%s", strings.Join(problems, "\n"))
 	}
+	return nil
 }
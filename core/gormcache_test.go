@@ -0,0 +1,47 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitializeCacheWithStore(t *testing.T) {
+	db := newTestDB(t)
+	daoMap, defaultStore := CacheDaoMap, DefaultStore
+	t.Cleanup(func() { CacheDaoMap, DefaultStore = daoMap, defaultStore })
+
+	var good *testUserCacheDao
+	newDao := func(base *CacheDaoBase) {
+		base.Do = &testUser{}
+		base.SQLDao = &testUserSQLDao{db: db}
+		t.Cleanup(func() { base.Close() })
+	}
+	CacheDaoMap = map[string]func() interface{}{
+		"d_bad_key":  func() interface{} { dao := &badKeyCacheDao{}; newDao(&dao.CacheDaoBase); return dao },
+		"b_bad_type": func() interface{} { dao := &badTypeCacheDao{}; newDao(&dao.CacheDaoBase); return dao },
+		"a_good":     func() interface{} { good = &testUserCacheDao{}; newDao(&good.CacheDaoBase); return good },
+		"c_not_dao":  func() interface{} { return &testUser{} },
+	}
+	store := newMemStore()
+	err := InitializeCacheWithStore(store)
+	if err == nil {
+		t.Fatal("initialize should fail")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	want := []string{
+		"initialize cache daos failed:",
+		"b_bad_type: invalid notify info of testUser:",
+		"method 'GetByName': unknown notify type 'concret'",
+		"c_not_dao: cache dao should extend 'CacheDaoBase'",
+		"d_bad_key: invalid notify info of testUser:",
+		"method 'GetByName': key 'Nmae' is not a field of 'testUser'",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got error:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+
+	// the daos without problem are initialized with the default store
+	if good == nil || good.MethodNotifyInfoMap == nil || good.cacheStore() != store {
+		t.Fatal("good cache dao should be initialized with default store")
+	}
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testUser Do of tests
+type testUser struct {
+	Id     uint64 `gorm:"primaryKey"`
+	Name   string
+	Status int
+}
+
+// testUserSQLDao sql dao of testUser
+type testUserSQLDao struct {
+	db *gorm.DB
+}
+
+func (d *testUserSQLDao) GetReadDbSource() *gorm.DB {
+	return d.db
+}
+
+func (d *testUserSQLDao) GetByName(name string) (*testUser, error) {
+	user := &testUser{}
+	err := d.db.Where("name = ?", name).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (d *testUserSQLDao) GetByStatus(db *gorm.DB, status int) ([]testUser, error) {
+	users := make([]testUser, 0)
+	err := db.Where("status = ?", status).Order("id").Find(&users).Error
+	return users, err
+}
+
+// testUserCacheDao cache dao of testUser
+type testUserCacheDao struct {
+	CacheDaoBase
+
+	metaGetByName   bool `notify:"func=GetByName;type=concrete;keys=['Name'];args=[0]"`
+	metaGetByStatus bool `notify:"func=GetByStatus;type=range;keys=['Status'];args=[1]"`
+}
+
+func (d *testUserCacheDao) GetByName(name string) (*testUser, error) {
+	return ToObject[testUser](d.GetByConcreteKey(name))
+}
+
+func (d *testUserCacheDao) GetByStatus(db *gorm.DB, status int) ([]testUser, error) {
+	return ToList[testUser](d.GetByRange(db, status))
+}
+
+// newTestDB open sqlite db with table of testUser in temp dir
+func newTestDB(t *testing.T) *gorm.DB {
+	return newTestDBWithConfig(t, &gorm.Config{})
+}
+
+// newTestDBWithConfig like 'newTestDB', logger of config is discarded
+func newTestDBWithConfig(t *testing.T, config *gorm.Config) *gorm.DB {
+	config.Logger = logger.Discard
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&testUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newTestCacheDao new initialized cache dao of testUser with its own memory store,
+// setup is called before initialize. it's closed when the test finishes.
+func newTestCacheDao(t *testing.T, db *gorm.DB, setup func(dao *testUserCacheDao)) *testUserCacheDao {
+	dao := &testUserCacheDao{}
+	dao.Do = &testUser{}
+	dao.SQLDao = &testUserSQLDao{db: db}
+	dao.Store = newMemStore()
+	if setup != nil {
+		setup(dao)
+	}
+	err := dao.Initialize(dao)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dao.Close() })
+	return dao
+}
+
+// memStore CacheStore in memory, expiration is ignored
+type memStore struct {
+	lock     sync.Mutex
+	items    map[string]*memItem
+	revision int64
+}
+
+type memItem struct {
+	value    []byte
+	revision int64 // used as cas token
+}
+
+func newMemStore() *memStore {
+	return &memStore{items: make(map[string]*memItem)}
+}
+
+func (s *memStore) Get(ctx context.Context, key string) (*Item, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return &Item{Key: key, Value: append([]byte(nil), item.value...), casToken: item.revision}, nil
+}
+
+func (s *memStore) GetMulti(ctx context.Context, keys []string) (map[string]*Item, error) {
+	ret := make(map[string]*Item)
+	for _, key := range keys {
+		item, err := s.Get(ctx, key)
+		if err == nil {
+			ret[key] = item
+		}
+	}
+	return ret, nil
+}
+
+func (s *memStore) Set(ctx context.Context, item *Item) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.set(item)
+	return nil
+}
+
+func (s *memStore) set(item *Item) {
+	s.revision++
+	s.items[item.Key] = &memItem{value: append([]byte(nil), item.Value...), revision: s.revision}
+}
+
+func (s *memStore) Add(ctx context.Context, item *Item) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.items[item.Key]; ok {
+		return ErrNotStored
+	}
+	s.set(item)
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.items[key]; !ok {
+		return ErrCacheMiss
+	}
+	delete(s.items, key)
+	return nil
+}
+
+func (s *memStore) CAS(ctx context.Context, item *Item) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	old, ok := s.items[item.Key]
+	if !ok {
+		return ErrCacheMiss
+	}
+	if old.revision != item.casToken {
+		return ErrCASConflict
+	}
+	s.set(item)
+	return nil
+}
+
+func (s *memStore) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.items[key]
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	val := util.ConvertStringToNumber(string(item.value)) + int64(delta)
+	s.set(&Item{Key: key, Value: []byte(util.ConvertNumberToString(val))})
+	return uint64(val), nil
+}
+
+// recordLogger log.Interface recording the messages of warn and error
+type recordLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *recordLogger) Debug(msg string, fields ...log.Field) {}
+
+func (l *recordLogger) Info(msg string, fields ...log.Field) {}
+
+func (l *recordLogger) Warn(msg string, fields ...log.Field) {
+	l.record(msg)
+}
+
+func (l *recordLogger) Error(msg string, fields ...log.Field) {
+	l.record(msg)
+}
+
+func (l *recordLogger) record(msg string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+// logged whether any message recorded contains s
+func (l *recordLogger) logged(s string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, msg := range l.messages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
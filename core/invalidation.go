@@ -0,0 +1,259 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zhyeah/gorm-cache/log"
+)
+
+// InvalidationMessage invalidation emitted by 'NotifyModified' and 'UpdateVersion'
+type InvalidationMessage struct {
+	Source      string   `json:"source"`       // instance id of the publisher dao, receivers skip their own messages
+	Prefix      string   `json:"prefix"`       // 'ObjectCachePrefix' of the publisher dao
+	Ids         []string `json:"ids"`          // ids of modified objects
+	VersionKeys []string `json:"version_keys"` // updated version keys
+}
+
+// InvalidationBus broadcast invalidations to the local cache tiers of other processes
+type InvalidationBus interface {
+	Publish(msg *InvalidationMessage) error
+	Subscribe(handler func(msg *InvalidationMessage)) error
+	Close() error
+}
+
+// DefaultInvalidationBus global invalidation bus, used by CacheDaoBase which has no 'InvalidationBus' specified
+var DefaultInvalidationBus InvalidationBus
+
+// invalidationSubscription daos subscribing a bus, grouped by dao prefix
+type invalidationSubscription struct {
+	daos map[string][]*CacheDaoBase
+}
+
+// invalidation subscriptions by bus, the bus is dropped once all its daos are closed
+var (
+	invalidationLock          sync.Mutex
+	invalidationSubscriptions = make(map[InvalidationBus]*invalidationSubscription)
+)
+
+// subscribeInvalidation subscribe bus for dao, each bus is subscribed once while it has daos
+func subscribeInvalidation(bus InvalidationBus, base *CacheDaoBase) error {
+	invalidationLock.Lock()
+	defer invalidationLock.Unlock()
+
+	sub, ok := invalidationSubscriptions[bus]
+	if !ok {
+		sub = &invalidationSubscription{daos: make(map[string][]*CacheDaoBase)}
+		err := bus.Subscribe(sub.dispatch)
+		if err != nil {
+			return err
+		}
+		invalidationSubscriptions[bus] = sub
+	}
+	for _, v := range sub.daos[base.ObjectCachePrefix] {
+		if v == base {
+			return nil
+		}
+	}
+	sub.daos[base.ObjectCachePrefix] = append(sub.daos[base.ObjectCachePrefix], base)
+	return nil
+}
+
+// unsubscribeInvalidation remove dao from the subscription of bus, the bus is dropped if no dao is left.
+// the handler of a dropped subscription stays in the bus but dispatches to nothing,
+// the bus is subscribed again by the daos coming later.
+func unsubscribeInvalidation(bus InvalidationBus, base *CacheDaoBase) {
+	invalidationLock.Lock()
+	defer invalidationLock.Unlock()
+
+	sub, ok := invalidationSubscriptions[bus]
+	if !ok {
+		return
+	}
+	daos := make([]*CacheDaoBase, 0, len(sub.daos[base.ObjectCachePrefix]))
+	for _, v := range sub.daos[base.ObjectCachePrefix] {
+		if v != base {
+			daos = append(daos, v)
+		}
+	}
+	if len(daos) > 0 {
+		sub.daos[base.ObjectCachePrefix] = daos
+		return
+	}
+	delete(sub.daos, base.ObjectCachePrefix)
+	if len(sub.daos) == 0 {
+		delete(invalidationSubscriptions, bus)
+	}
+}
+
+func (sub *invalidationSubscription) dispatch(msg *InvalidationMessage) {
+	invalidationLock.Lock()
+	bases := sub.daos[msg.Prefix]
+	invalidationLock.Unlock()
+
+	for _, base := range bases {
+		base.handleInvalidation(msg)
+	}
+}
+
+// handleInvalidation drop the local copies invalidated by other processes
+func (base *CacheDaoBase) handleInvalidation(msg *InvalidationMessage) {
+	if msg.Source == base.instanceID {
+		return
+	}
+	base.logger().Debug("receive invalidation", log.F("source", msg.Source), log.F("ids", msg.Ids), log.F("versionKeys", msg.VersionKeys))
+	if base.localCache != nil {
+		for _, id := range msg.Ids {
+			base.localCache.remove(id)
+		}
+	}
+	if base.OnInvalidation != nil {
+		base.OnInvalidation(msg)
+	}
+}
+
+// publishInvalidation publish invalidation if there is a bus
+func (base *CacheDaoBase) publishInvalidation(ids []string, versionKeys []string) {
+	bus := base.invalidationBus()
+	if bus == nil {
+		return
+	}
+	err := bus.Publish(&InvalidationMessage{
+		Source:      base.instanceID,
+		Prefix:      base.ObjectCachePrefix,
+		Ids:         ids,
+		VersionKeys: versionKeys,
+	})
+	if err != nil {
+		base.logger().Error("publish invalidation failed", log.F("ids", ids), log.F("versionKeys", versionKeys), log.Err(err))
+	}
+}
+
+// invalidationBus get the invalidation bus of this dao
+func (base *CacheDaoBase) invalidationBus() InvalidationBus {
+	if base.InvalidationBus != nil {
+		return base.InvalidationBus
+	}
+	return DefaultInvalidationBus
+}
+
+func newInstanceID() string {
+	bts := make([]byte, 8)
+	_, err := rand.Read(bts)
+	if err != nil {
+		log.Default.Error("generate instance id failed", log.Err(err))
+	}
+	return hex.EncodeToString(bts)
+}
+
+// LoopbackBus in-memory invalidation bus, delivers messages to the subscribers of the same bus synchronously.
+// it's useful for tests and for multiple daos sharing one prefix in a single process.
+type LoopbackBus struct {
+	lock     sync.RWMutex
+	handlers []func(msg *InvalidationMessage)
+}
+
+// NewLoopbackBus new loopback bus
+func NewLoopbackBus() *LoopbackBus {
+	return &LoopbackBus{}
+}
+
+// Publish deliver message to all subscribers
+func (b *LoopbackBus) Publish(msg *InvalidationMessage) error {
+	b.lock.RLock()
+	handlers := b.handlers
+	b.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Subscribe add subscriber
+func (b *LoopbackBus) Subscribe(handler func(msg *InvalidationMessage)) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// Close remove all subscribers
+func (b *LoopbackBus) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.handlers = nil
+	return nil
+}
+
+// RedisBus invalidation bus based on redis pub/sub
+type RedisBus struct {
+	Client  redis.UniversalClient
+	Channel string
+
+	lock   sync.Mutex
+	pubsub []*redis.PubSub
+}
+
+// NewRedisBus new redis pub/sub bus, channel default 'gormcache:invalidation'
+func NewRedisBus(client redis.UniversalClient, channel string) *RedisBus {
+	if channel == "" {
+		channel = "gormcache:invalidation"
+	}
+	return &RedisBus{Client: client, Channel: channel}
+}
+
+// Publish publish message to channel
+func (b *RedisBus) Publish(msg *InvalidationMessage) error {
+	bts, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.Client.Publish(context.Background(), b.Channel, bts).Err()
+}
+
+// Subscribe subscribe channel, handler is invoked in a background goroutine
+func (b *RedisBus) Subscribe(handler func(msg *InvalidationMessage)) error {
+	pubsub := b.Client.Subscribe(context.Background(), b.Channel)
+	// wait for subscription confirmed, so messages published after Subscribe returns won't be lost
+	_, err := pubsub.Receive(context.Background())
+	if err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	b.lock.Lock()
+	b.pubsub = append(b.pubsub, pubsub)
+	b.lock.Unlock()
+
+	go func() {
+		for redisMsg := range pubsub.Channel() {
+			msg := &InvalidationMessage{}
+			err := json.Unmarshal([]byte(redisMsg.Payload), msg)
+			if err != nil {
+				log.Default.Error("unmarshal invalidation message failed", log.F("payload", redisMsg.Payload), log.Err(err))
+				continue
+			}
+			handler(msg)
+		}
+	}()
+	return nil
+}
+
+// Close close all subscriptions
+func (b *RedisBus) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var lastErr error
+	for _, pubsub := range b.pubsub {
+		if err := pubsub.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	b.pubsub = nil
+	return lastErr
+}
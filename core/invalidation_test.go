@@ -0,0 +1,255 @@
+package core
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+func TestLoopbackBusInvalidation(t *testing.T) {
+	db := newTestDB(t)
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bus := NewLoopbackBus()
+	store := newMemStore()
+	received := make(map[string][]*InvalidationMessage)
+	lock := sync.Mutex{}
+	newDao := func(name string) *testUserCacheDao {
+		return newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+			dao.Store = store
+			dao.LocalCache = &LocalCacheConfig{ExpireMillis: 60000}
+			dao.InvalidationBus = bus
+			dao.OnInvalidation = func(msg *InvalidationMessage) {
+				lock.Lock()
+				defer lock.Unlock()
+				received[name] = append(received[name], msg)
+			}
+		})
+	}
+	a, b := newDao("a"), newDao("b")
+
+	// both daos load the object into their local caches
+	for _, dao := range []*testUserCacheDao{a, b} {
+		obj, err := dao.GetById(user.Id)
+		if err != nil || obj.(*testUser).Name != "alice" {
+			t.Fatalf("GetById: got %v %v", obj, err)
+		}
+		if _, ok := dao.getLocalObject(user.Id); !ok {
+			t.Fatal("object not in local cache")
+		}
+	}
+
+	user.Name = "bob"
+	if err := db.Save(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := a.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// the message published by a is ignored by a itself, and evicts the local copy of b
+	if len(received["a"]) != 0 {
+		t.Fatalf("self-published messages handled: %v", received["a"])
+	}
+	if len(received["b"]) != 1 {
+		t.Fatalf("messages received by peer: got %d, want 1", len(received["b"]))
+	}
+	msg := received["b"][0]
+	if msg.Source != a.instanceID || msg.Prefix != a.ObjectCachePrefix || len(msg.Ids) != 1 || msg.Ids[0] != a.encodeKey(user.Id) {
+		t.Fatalf("message received by peer: %+v", msg)
+	}
+	if _, ok := b.getLocalObject(user.Id); ok {
+		t.Fatal("local copy of peer not evicted")
+	}
+	obj, err := b.GetById(user.Id)
+	if err != nil || obj.(*testUser).Name != "bob" {
+		t.Fatalf("GetById of peer after invalidation: got %v %v", obj, err)
+	}
+}
+
+func TestHandleInvalidationIgnoresOwnMessages(t *testing.T) {
+	db := newTestDB(t)
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	handled := 0
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.LocalCache = &LocalCacheConfig{ExpireMillis: 60000}
+		dao.InvalidationBus = NewLoopbackBus()
+		dao.OnInvalidation = func(msg *InvalidationMessage) { handled++ }
+	})
+	if _, err := dao.GetById(user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	dao.handleInvalidation(&InvalidationMessage{Source: dao.instanceID, Prefix: dao.ObjectCachePrefix, Ids: []string{dao.encodeKey(user.Id)}})
+	if _, ok := dao.getLocalObject(user.Id); !ok || handled != 0 {
+		t.Fatal("own message handled")
+	}
+
+	dao.handleInvalidation(&InvalidationMessage{Source: "other", Prefix: dao.ObjectCachePrefix, Ids: []string{dao.encodeKey(user.Id)}})
+	if _, ok := dao.getLocalObject(user.Id); ok || handled != 1 {
+		t.Fatal("message of other instance not handled")
+	}
+}
+
+// receivedMessages count the messages received by daos
+type receivedMessages struct {
+	lock  sync.Mutex
+	count map[string]int
+	ch    chan string
+}
+
+func newReceivedMessages() *receivedMessages {
+	return &receivedMessages{count: make(map[string]int), ch: make(chan string, 16)}
+}
+
+func (r *receivedMessages) handler(name string) func(msg *InvalidationMessage) {
+	return func(msg *InvalidationMessage) {
+		r.lock.Lock()
+		r.count[name]++
+		r.lock.Unlock()
+		r.ch <- name
+	}
+}
+
+func (r *receivedMessages) get(name string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.count[name]
+}
+
+// wait wait for the message received by name
+func (r *receivedMessages) wait(t *testing.T, name string) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case got := <-r.ch:
+			if got == name {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("message not received by %s", name)
+		}
+	}
+}
+
+func newBusTestDao(t *testing.T, db *gorm.DB, bus InvalidationBus, onInvalidation func(msg *InvalidationMessage)) *testUserCacheDao {
+	return newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.InvalidationBus = bus
+		dao.OnInvalidation = onInvalidation
+	})
+}
+
+func TestCloseUnsubscribes(t *testing.T) {
+	db := newTestDB(t)
+	user := &testUser{Id: 1, Name: "alice", Status: 1}
+	bus := NewLoopbackBus()
+	received := newReceivedMessages()
+	a := newBusTestDao(t, db, bus, received.handler("a"))
+	b := newBusTestDao(t, db, bus, received.handler("b"))
+	c := newBusTestDao(t, db, bus, received.handler("c"))
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dao := range findCacheDaos(reflect.TypeOf(testUser{})) {
+		if dao == &b.CacheDaoBase {
+			t.Fatal("closed dao is still registered for the plugin")
+		}
+	}
+	if err := a.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+	if received.get("b") != 0 || received.get("c") != 1 {
+		t.Fatalf("got messages %v, closed dao shouldn't receive any", received.count)
+	}
+
+	// the bus is pruned with its last dao
+	a.Close()
+	c.Close()
+	invalidationLock.Lock()
+	_, ok := invalidationSubscriptions[bus]
+	invalidationLock.Unlock()
+	if ok {
+		t.Fatal("bus of closed daos not pruned")
+	}
+
+	// subscribed again, the message isn't dispatched twice
+	d := newBusTestDao(t, db, bus, received.handler("d"))
+	if err := d.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+	newBusTestDao(t, db, bus, received.handler("e"))
+	if err := d.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+	if received.get("e") != 1 || received.get("c") != 1 {
+		t.Fatalf("got messages %v after subscribed again", received.count)
+	}
+}
+
+func TestRedisBusInvalidation(t *testing.T) {
+	db := newTestDB(t)
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	// each bus has its own client, as the daos of different processes
+	newBus := func() *RedisBus {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		bus := NewRedisBus(client, "")
+		t.Cleanup(func() {
+			bus.Close()
+			client.Close()
+		})
+		return bus
+	}
+	received := newReceivedMessages()
+	a := newBusTestDao(t, db, newBus(), received.handler("a"))
+	peerBus := newBus()
+	b := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.LocalCache = &LocalCacheConfig{ExpireMillis: 60000}
+		dao.InvalidationBus = peerBus
+		dao.OnInvalidation = received.handler("b")
+	})
+	newBusTestDao(t, db, peerBus, received.handler("c"))
+
+	if _, err := b.GetById(user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.NotifyModified(user); err != nil {
+		t.Fatal(err)
+	}
+	received.wait(t, "b")
+	if _, ok := b.getLocalObject(user.Id); ok {
+		t.Fatal("local copy of peer not evicted")
+	}
+
+	// messages are dispatched in order, b would have got the first one before c got the second one
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := a.NotifyModified(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for received.get("c") < 3 {
+		received.wait(t, "c")
+	}
+	if received.get("a") != 0 || received.get("b") != 1 {
+		t.Fatalf("got messages %v, closed dao shouldn't receive any", received.count)
+	}
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"time"
+
+	"github.com/bluele/gcache"
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/log"
+)
+
+// LocalCacheConfig in-process object cache tier config
+type LocalCacheConfig struct {
+	Size         int    // max object count, default 1024
+	ExpireMillis int64  // ttl of each object, default 1000ms
+	Policy       string // refer: constant, default lru
+}
+
+// localCache in-process object cache tier, consulted before cache store.
+// it stores serialized data, so every reader gets its own copy of object.
+type localCache struct {
+	cache gcache.Cache
+}
+
+func newLocalCache(config *LocalCacheConfig) *localCache {
+	size := config.Size
+	if size <= 0 {
+		size = 1024
+	}
+	expireMillis := config.ExpireMillis
+	if expireMillis <= 0 {
+		expireMillis = 1000
+	}
+
+	builder := gcache.New(size)
+	switch config.Policy {
+	case constant.LocalCachePolicyLFU:
+		builder = builder.LFU()
+	case constant.LocalCachePolicyARC:
+		builder = builder.ARC()
+	default:
+		builder = builder.LRU()
+	}
+	return &localCache{
+		cache: builder.Expiration(time.Duration(expireMillis) * time.Millisecond).Build(),
+	}
+}
+
+func (c *localCache) get(key string) ([]byte, bool) {
+	val, err := c.cache.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return val.([]byte), true
+}
+
+func (c *localCache) set(key string, data []byte) {
+	c.cache.Set(key, data)
+}
+
+func (c *localCache) remove(key string) {
+	c.cache.Remove(key)
+}
+
+// getLocalObject get object from local cache tier
+func (base *CacheDaoBase) getLocalObject(key interface{}) (interface{}, bool) {
+	if base.localCache == nil {
+		return nil, false
+	}
+	data, ok := base.localCache.get(base.encodeKey(key))
+	if !ok {
+		return nil, false
+	}
+	objInstancePtr := base.makeObjInstancePtr()
+	err := base.deserializeObject(data, objInstancePtr)
+	if err != nil {
+		base.logger().Warn("deserialize local cache failed", log.Key(key), log.Err(err))
+		base.stats(metricsMethodGetById).deserializeFailure(1)
+		base.localCache.remove(base.encodeKey(key))
+		return nil, false
+	}
+	return objInstancePtr, true
+}
+
+// setLocalObject set serialized object to local cache tier
+func (base *CacheDaoBase) setLocalObject(key interface{}, data []byte) {
+	if base.localCache == nil {
+		return
+	}
+	base.localCache.set(base.encodeKey(key), data)
+}
+
+// removeLocalObject remove object from local cache tier
+func (base *CacheDaoBase) removeLocalObject(key interface{}) {
+	if base.localCache == nil {
+		return
+	}
+	base.localCache.remove(base.encodeKey(key))
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// MemcacheStore CacheStore implementation based on gomemcache
+type MemcacheStore struct {
+	Client *memcache.Client
+}
+
+// NewMemcacheStore new memcache store with client
+func NewMemcacheStore(client *memcache.Client) *MemcacheStore {
+	return &MemcacheStore{Client: client}
+}
+
+// Get get item by key
+func (s *MemcacheStore) Get(ctx context.Context, key string) (*Item, error) {
+	var item *memcache.Item
+	err := doWithContext(ctx, func() (err error) {
+		item, err = s.Client.Get(key)
+		return err
+	})
+	if err != nil {
+		return nil, convertMemcacheError(err)
+	}
+	return fromMemcacheItem(item), nil
+}
+
+// GetMulti get items by keys
+func (s *MemcacheStore) GetMulti(ctx context.Context, keys []string) (map[string]*Item, error) {
+	var items map[string]*memcache.Item
+	err := doWithContext(ctx, func() (err error) {
+		items, err = s.Client.GetMulti(keys)
+		return err
+	})
+	if err != nil {
+		return nil, convertMemcacheError(err)
+	}
+	ret := make(map[string]*Item, len(items))
+	for k, v := range items {
+		ret[k] = fromMemcacheItem(v)
+	}
+	return ret, nil
+}
+
+// Set set item
+func (s *MemcacheStore) Set(ctx context.Context, item *Item) error {
+	return convertMemcacheError(doWithContext(ctx, func() error {
+		return s.Client.Set(toMemcacheItem(item))
+	}))
+}
+
+// Add add item if absent
+func (s *MemcacheStore) Add(ctx context.Context, item *Item) error {
+	return convertMemcacheError(doWithContext(ctx, func() error {
+		return s.Client.Add(toMemcacheItem(item))
+	}))
+}
+
+// Delete delete item by key
+func (s *MemcacheStore) Delete(ctx context.Context, key string) error {
+	return convertMemcacheError(doWithContext(ctx, func() error {
+		return s.Client.Delete(key)
+	}))
+}
+
+// CAS compare and swap item
+func (s *MemcacheStore) CAS(ctx context.Context, item *Item) error {
+	return convertMemcacheError(doWithContext(ctx, func() error {
+		return s.Client.CompareAndSwap(toMemcacheItem(item))
+	}))
+}
+
+// Incr increase the value of key
+func (s *MemcacheStore) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	var val uint64
+	err := doWithContext(ctx, func() (err error) {
+		val, err = s.Client.Increment(key, delta)
+		return err
+	})
+	if err != nil {
+		return 0, convertMemcacheError(err)
+	}
+	return val, nil
+}
+
+// doWithContext gomemcache doesn't accept context, so run fn in background and stop waiting once ctx is done.
+func doWithContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- fn()
+	}()
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func fromMemcacheItem(item *memcache.Item) *Item {
+	return &Item{
+		Key:        item.Key,
+		Value:      item.Value,
+		Expiration: item.Expiration,
+		casToken:   item,
+	}
+}
+
+func toMemcacheItem(item *Item) *memcache.Item {
+	// memcache keeps cas id in unexported field, so reuse the item we got
+	if origin, ok := item.casToken.(*memcache.Item); ok {
+		origin.Value = item.Value
+		origin.Expiration = item.Expiration
+		return origin
+	}
+	return &memcache.Item{Key: item.Key, Value: item.Value, Expiration: item.Expiration}
+}
+
+func convertMemcacheError(err error) error {
+	switch err {
+	case memcache.ErrCacheMiss:
+		return ErrCacheMiss
+	case memcache.ErrNotStored:
+		return ErrNotStored
+	case memcache.ErrCASConflict:
+		return ErrCASConflict
+	}
+	return err
+}
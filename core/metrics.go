@@ -0,0 +1,106 @@
+package core
+
+import "time"
+
+// method labels of object cache queries
+const (
+	metricsMethodGetById  = "GetById"
+	metricsMethodGetByIds = "GetByIds"
+)
+
+// Metrics hook of cache events, labeled by dao (object cache prefix) and method (sql dao method name, or 'GetById'/'GetByIds'),
+// n is the count of keys. implement it to collect metrics, or use the prometheus implementation in package 'metrics'.
+type Metrics interface {
+	Hit(dao, method string, n int)                // value got from cache, including the cached absent ones
+	Miss(dao, method string, n int)               // version got, but the value is absent in cache
+	VersionMiss(dao, method string, n int)        // version key is absent, or failed to get it
+	DeserializeFailure(dao, method string, n int) // cached value can't be deserialized
+	DBFallback(dao, method string, n int)         // loaded from sql dao (or read db for objects)
+	SetFailure(dao, method string, n int)         // failed to set cache
+	Latency(dao, method string, d time.Duration)  // elapsed time of the cached query
+}
+
+// DefaultMetrics metrics hook used by cache daos which have no 'Metrics' set, no metrics if nil
+var DefaultMetrics Metrics
+
+// metrics get the metrics hook of this dao
+func (base *CacheDaoBase) metrics() Metrics {
+	if base.Metrics != nil {
+		return base.Metrics
+	}
+	if DefaultMetrics != nil {
+		return DefaultMetrics
+	}
+	return nopMetrics{}
+}
+
+// stats metrics of method
+func (base *CacheDaoBase) stats(method string) methodStats {
+	return methodStats{metrics: base.metrics(), dao: base.ObjectCachePrefix, method: method}
+}
+
+// methodStats shortcut to report metrics of a method
+type methodStats struct {
+	metrics Metrics
+	dao     string
+	method  string
+	span    *phaseSpan // hits are counted on it too
+}
+
+// traced count hits on span too
+func (s methodStats) traced(span *phaseSpan) methodStats {
+	s.span = span
+	return s
+}
+
+func (s methodStats) hit(n int) {
+	if n > 0 {
+		s.metrics.Hit(s.dao, s.method, n)
+		s.span.hit(n)
+	}
+}
+
+func (s methodStats) miss(n int) {
+	if n > 0 {
+		s.metrics.Miss(s.dao, s.method, n)
+	}
+}
+
+func (s methodStats) versionMiss(n int) {
+	if n > 0 {
+		s.metrics.VersionMiss(s.dao, s.method, n)
+	}
+}
+
+func (s methodStats) deserializeFailure(n int) {
+	if n > 0 {
+		s.metrics.DeserializeFailure(s.dao, s.method, n)
+	}
+}
+
+func (s methodStats) dbFallback(n int) {
+	if n > 0 {
+		s.metrics.DBFallback(s.dao, s.method, n)
+	}
+}
+
+func (s methodStats) setFailure(n int) {
+	if n > 0 {
+		s.metrics.SetFailure(s.dao, s.method, n)
+	}
+}
+
+// since report latency since start, use it with defer
+func (s methodStats) since(start time.Time) {
+	s.metrics.Latency(s.dao, s.method, time.Since(start))
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Hit(dao, method string, n int)                {}
+func (nopMetrics) Miss(dao, method string, n int)               {}
+func (nopMetrics) VersionMiss(dao, method string, n int)        {}
+func (nopMetrics) DeserializeFailure(dao, method string, n int) {}
+func (nopMetrics) DBFallback(dao, method string, n int)         {}
+func (nopMetrics) SetFailure(dao, method string, n int)         {}
+func (nopMetrics) Latency(dao, method string, d time.Duration)  {}
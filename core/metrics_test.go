@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setFailureMetrics record the set failures by method
+type setFailureMetrics struct {
+	nopMetrics
+	lock     sync.Mutex
+	failures map[string]int
+}
+
+func (m *setFailureMetrics) SetFailure(dao, method string, n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.failures[method] += n
+}
+
+func (m *setFailureMetrics) get(method string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.failures[method]
+}
+
+// setFailingStore memory store which fails all sets
+type setFailingStore struct {
+	*memStore
+}
+
+func (s *setFailingStore) Set(ctx context.Context, item *Item) error {
+	return errors.New("set failed")
+}
+
+func TestMetricsSetFailureMethod(t *testing.T) {
+	db := newTestDB(t)
+	metrics := &setFailureMetrics{failures: make(map[string]int)}
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.Store = &setFailingStore{memStore: newMemStore()}
+		dao.Metrics = metrics
+	})
+	alice := &testUser{Name: "alice", Status: 1}
+	bob := &testUser{Name: "bob", Status: 1}
+	db.Create(alice)
+	db.Create(bob)
+
+	// object cache and method cache
+	if _, err := dao.GetByName("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if n := metrics.get("GetByName"); n != 2 {
+		t.Fatalf("set failures of GetByName: got %d, want 2", n)
+	}
+
+	if _, err := dao.GetById(alice.Id); err != nil {
+		t.Fatal(err)
+	}
+	if n := metrics.get(metricsMethodGetById); n != 1 {
+		t.Fatalf("set failures of GetById: got %d, want 1", n)
+	}
+
+	// object caches are set in background
+	if _, err := dao.GetByIds([]uint64{alice.Id, bob.Id}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && metrics.get(metricsMethodGetByIds) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := metrics.get(metricsMethodGetByIds); n != 2 {
+		t.Fatalf("set failures of GetByIds: got %d, want 2", n)
+	}
+	if n := metrics.get(metricsMethodGetById); n != 1 {
+		t.Fatalf("set failures of GetByIds reported as GetById: got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+)
+
+// GetAllByConcreteKey get all objects by concrete key of non-unique index (like 'status + tenant_id'),
+// the primary keys matched are cached under the version key of field values as 'concrete' does, the objects are got by 'GetByIds'.
+func (base *CacheDaoBase) GetAllByConcreteKey(args ...interface{}) (interface{}, error) {
+	return base.getAllByConcreteKey(context.Background(), util.GetLastExecuteFuncName(), args...)
+}
+
+// GetAllByConcreteKeyContext get all objects by concrete key of non-unique index, with context
+func (base *CacheDaoBase) GetAllByConcreteKeyContext(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return base.getAllByConcreteKey(ctx, util.GetLastExecuteFuncName(), args...)
+}
+
+func (base *CacheDaoBase) getAllByConcreteKey(ctx context.Context, sqlMethodName string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := base.startSpan(ctx, "GetAllByConcreteKey", attrMethod.String(sqlMethodName))
+	defer span.end(&err)
+	span.keys(1)
+	stats := base.stats(sqlMethodName).traced(span)
+	defer stats.since(time.Now())
+
+	// try to get from cache first.
+	cacheKey, err := base.GetKeyContext(ctx, sqlMethodName, args...)
+	if err != nil || cacheKey == "" {
+		base.logMiss("GetAllByConcreteKey missed version", err, log.Method(sqlMethodName), log.Args(args))
+		stats.versionMiss(1)
+		return base.loadAllByConcreteKey(ctx, sqlMethodName, args...)
+	}
+
+	cacheItem, err := base.getMethodCache(ctx, sqlMethodName, cacheKey)
+	if err != nil {
+		base.logMiss("GetAllByConcreteKey missed cache", err, log.Method(sqlMethodName), log.Args(args))
+		stats.miss(1)
+		return base.loadAllByConcreteKey(ctx, sqlMethodName, args...)
+	}
+
+	base.logger().Debug("GetAllByConcreteKey hit cache", log.Method(sqlMethodName), log.Key(cacheKey))
+	stats.hit(1)
+	if isNegativeValue(cacheItem.Value) {
+		return base.makeObjListPtr(), nil
+	}
+	keys, err := base.unmarshalKeys(cacheItem.Value)
+	if err != nil {
+		stats.deserializeFailure(1)
+		return nil, err
+	}
+	return base.getByPrimaryKeys(ctx, keys)
+}
+
+// loadAllByConcreteKey invoke sql dao method and cache the primary keys of result
+func (base *CacheDaoBase) loadAllByConcreteKey(ctx context.Context, sqlMethodName string, args ...interface{}) (interface{}, error) {
+	objs, err := base.invokeSQLDao(ctx, sqlMethodName, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	retList := base.makeObjListPtr()
+	if !util.IsNil(objs) {
+		listVal := reflect.ValueOf(retList).Elem()
+		_, objsValue := util.GetRealTypeAndValue(objs)
+		for i := 0; i < objsValue.Len(); i++ {
+			listVal.Set(reflect.Append(listVal, objsValue.Index(i)))
+		}
+	}
+
+	err = base.SetKeysCacheContext(ctx, retList, sqlMethodName, args...)
+	if err != nil {
+		base.logger().Error("GetAllByConcreteKey set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+	}
+	return retList, nil
+}
+
+// SetKeysCache set cache for concrete key query of non-unique index, objs are all the objects matched
+func (base *CacheDaoBase) SetKeysCache(objs interface{}, methodName string, args ...interface{}) error {
+	return base.SetKeysCacheContext(context.Background(), objs, methodName, args...)
+}
+
+// SetKeysCacheContext set cache for concrete key query of non-unique index, with context
+func (base *CacheDaoBase) SetKeysCacheContext(ctx context.Context, objs interface{}, methodName string, args ...interface{}) error {
+	keys, err := base.getPrimaryKeys(objs)
+	if err != nil {
+		return err
+	}
+
+	// empty result is cached for a short time as absent
+	if len(keys) == 0 && base.negativeCacheEnabled() {
+		return base.setNegativeCache(ctx, methodName, args...)
+	}
+
+	// set object caches
+	if len(keys) > 0 {
+		_, objsValue := util.GetRealTypeAndValue(objs)
+		for i := 0; i < objsValue.Len(); i++ {
+			obj := objsValue.Index(i).Interface()
+			err := base.setObjectCache(ctx, obj, methodName)
+			if err != nil {
+				base.logger().Error("set object cache failed", log.Key(base.GetPrimaryKey(obj)), log.Err(err))
+			}
+		}
+	}
+
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return base.setMethodCache(ctx, base.MakeKeyPrefix(methodName, args...), keysJSON, base.ExpireTime, methodName, args...)
+}
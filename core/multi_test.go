@@ -0,0 +1,83 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/constant"
+)
+
+func (d *testUserSQLDao) ListByStatus(status int) ([]testUser, error) {
+	users := make([]testUser, 0)
+	err := d.db.Where("status = ?", status).Order("id").Find(&users).Error
+	return users, err
+}
+
+func TestMulti(t *testing.T) {
+	db := newTestDB(t)
+	var list *CachedMethod
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		list = dao.MustBindMethod(&MethodBinding{
+			Method: (*testUserSQLDao).ListByStatus,
+			Type:   constant.NotifyTypeMulti,
+			Keys:   []string{"Status"},
+			Args:   []int{0},
+		})
+	})
+	alice, bob := createTestUsers(t, db)
+	queries := countQueries(t, db)
+	check := func(status int, wantQueries int, want ...string) {
+		t.Helper()
+		users, err := ToList[testUser](list.Get(status))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(users))
+		for _, user := range users {
+			names = append(names, user.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(want, ",") || *queries != wantQueries {
+			t.Fatalf("list of status %d: got %v with %d queries, want %v with %d queries", status, names, *queries, want, wantQueries)
+		}
+	}
+
+	// the objects are cached along with the primary keys
+	check(1, 1, "alice", "bob")
+	check(1, 1, "alice", "bob")
+
+	// empty result is cached as absent
+	check(2, 2)
+	check(2, 2)
+	carol := &testUser{Name: "carol", Status: 2}
+	if err := db.Create(carol).Error; err != nil {
+		t.Fatal(err)
+	}
+	check(2, 2)
+	if err := dao.NotifyModified(carol); err != nil {
+		t.Fatal(err)
+	}
+	check(2, 3, "carol")
+
+	// modified object bumps the version of its status, so the list is reloaded
+	bob.Name = "bobby"
+	if err := db.Save(bob).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.NotifyModified(bob); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 4, "alice", "bobby")
+	check(1, 4, "alice", "bobby")
+
+	// both the old and new status are invalidated by update
+	before := *alice
+	alice.Status = 2
+	if err := db.Save(alice).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.NotifyUpdated(&before, alice); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 5, "bobby")
+	check(2, 6, "alice", "carol")
+}
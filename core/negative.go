@@ -0,0 +1,73 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+)
+
+// negativeValue the sentinel cached for absent rows and empty results,
+// it can't be produced by any serializer, so check it before deserialize.
+var negativeValue = []byte("\x00gormcache:negative")
+
+// isNegativeValue check if the cached value is the negative sentinel
+func isNegativeValue(data []byte) bool {
+	return bytes.Equal(data, negativeValue)
+}
+
+// negativeCacheEnabled check if absent rows and empty results should be cached
+func (base *CacheDaoBase) negativeCacheEnabled() bool {
+	return base.NegativeExpireTime > 0
+}
+
+// setNegativeObjectCache record primary key as absent for 'NegativeExpireTime',
+// cleared by 'NotifyModified' after the row is created as the object cache does.
+func (base *CacheDaoBase) setNegativeObjectCache(ctx context.Context, key interface{}) error {
+	if !base.negativeCacheEnabled() {
+		return nil
+	}
+	now := time.Now().UnixNano() / 1e6
+	version := util.ConvertNumberToString(now)
+	objCacheKey := base.makeObjectKey(key, version)
+	err := base.cacheStore().Set(ctx, &Item{Key: objCacheKey, Value: negativeValue, Expiration: int32(base.NegativeExpireTime)})
+	if err != nil {
+		return err
+	}
+	objVersionKey := base.makeObjectVersionKey(key)
+	return base.cacheStore().Set(ctx, &Item{Key: objVersionKey, Value: []byte(version), Expiration: int32(base.NegativeExpireTime)})
+}
+
+// setNegativeObjectCaches record primary keys which are not in objList as absent
+func (base *CacheDaoBase) setNegativeObjectCaches(ctx context.Context, keys []interface{}, objList interface{}) {
+	if !base.negativeCacheEnabled() {
+		return
+	}
+	foundKeys, err := base.getPrimaryKeys(objList)
+	if err != nil {
+		return
+	}
+	foundKeyMap := make(map[string]int)
+	for i := range foundKeys {
+		foundKeyMap[base.encodeKey(foundKeys[i])] = 1
+	}
+	for i := range keys {
+		if _, ok := foundKeyMap[base.encodeKey(keys[i])]; ok {
+			continue
+		}
+		err := base.setNegativeObjectCache(ctx, keys[i])
+		if err != nil {
+			base.logger().Error("set negative object cache failed", log.Key(keys[i]), log.Err(err))
+		}
+	}
+}
+
+// setNegativeCache record the result of method with args as absent (or empty)
+func (base *CacheDaoBase) setNegativeCache(ctx context.Context, methodName string, args ...interface{}) error {
+	if !base.negativeCacheEnabled() {
+		return nil
+	}
+	return base.setMethodCache(ctx, base.MakeKeyPrefix(methodName, args...), negativeValue, base.NegativeExpireTime, methodName, args...)
+}
@@ -0,0 +1,331 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// instance key of the rows queried before update/delete
+const pluginBeforeImagesKey = "gormcache:before_images"
+
+// cache daos grouped by the type of 'Do', filled by 'Initialize'
+var (
+	cacheDaoRegistryLock sync.RWMutex
+	cacheDaoRegistry     = make(map[reflect.Type][]*CacheDaoBase)
+)
+
+// registerCacheDao register dao for its 'Do' type
+func registerCacheDao(base *CacheDaoBase) {
+	doType := util.GetPointToType(reflect.TypeOf(base.Do))
+
+	cacheDaoRegistryLock.Lock()
+	defer cacheDaoRegistryLock.Unlock()
+	for _, v := range cacheDaoRegistry[doType] {
+		if v == base {
+			return
+		}
+	}
+	cacheDaoRegistry[doType] = append(cacheDaoRegistry[doType], base)
+}
+
+// unregisterCacheDao remove dao from the daos of its 'Do' type
+func unregisterCacheDao(base *CacheDaoBase) {
+	doType := util.GetPointToType(reflect.TypeOf(base.Do))
+
+	cacheDaoRegistryLock.Lock()
+	defer cacheDaoRegistryLock.Unlock()
+	daos := make([]*CacheDaoBase, 0, len(cacheDaoRegistry[doType]))
+	for _, v := range cacheDaoRegistry[doType] {
+		if v != base {
+			daos = append(daos, v)
+		}
+	}
+	if len(daos) == 0 {
+		delete(cacheDaoRegistry, doType)
+		return
+	}
+	cacheDaoRegistry[doType] = daos
+}
+
+// findCacheDaos find daos whose 'Do' type is modelType
+func findCacheDaos(modelType reflect.Type) []*CacheDaoBase {
+	cacheDaoRegistryLock.RLock()
+	defer cacheDaoRegistryLock.RUnlock()
+	return cacheDaoRegistry[modelType]
+}
+
+// InvalidationPlugin gorm plugin that invokes 'NotifyModified' automatically after create/update/delete,
+// for the cache daos whose 'Do' is the model of statement.
+// the conn pool of db is wrapped to hook the transactions began from it, as gorm has no callback of commit,
+// so the invalidations in transaction are deferred until commit, and dropped on rollback (or rollback to savepoint).
+// the sessions with 'PrepareStmt' can't begin transaction from the wrapped conn pool,
+// open db with 'gorm.Config.PrepareStmt' instead of 'gorm.Session.PrepareStmt' to use both.
+// usage: db.Use(core.NewInvalidationPlugin()), or db.Use(&core.InvalidationPlugin{QueryImages: true})
+type InvalidationPlugin struct {
+	// QueryImages query the rows before update/delete and after update in the same transaction,
+	// so the version keys of both old and new field values are notified, even for 'Updates' with map and
+	// batch 'Delete' with conditions. it costs one more SELECT for each delete and two more for each update.
+	// if it's off, the model of statement is notified, and the cached object is taken as the old row of update.
+	QueryImages bool
+}
+
+// NewInvalidationPlugin new invalidation plugin
+func NewInvalidationPlugin() *InvalidationPlugin {
+	return &InvalidationPlugin{}
+}
+
+// Name plugin name
+func (p *InvalidationPlugin) Name() string {
+	return "gormcache:invalidation"
+}
+
+// Initialize hook transactions and register callbacks
+func (p *InvalidationPlugin) Initialize(db *gorm.DB) error {
+	hookTransactions(db)
+	callback := db.Callback()
+
+	// rows to be updated/deleted are queried in the same transaction, so the old field values can be notified
+	if p.QueryImages {
+		err := callback.Update().After("gorm:before_update").Before("gorm:update").Register("gormcache:before_update", p.queryBeforeImages)
+		if err != nil {
+			return err
+		}
+		err = callback.Delete().After("gorm:before_delete").Before("gorm:delete").Register("gormcache:before_delete", p.queryBeforeImages)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := callback.Create().After("gorm:commit_or_rollback_transaction").Register("gormcache:after_create", p.afterCreate)
+	if err != nil {
+		return err
+	}
+	err = callback.Update().After("gorm:commit_or_rollback_transaction").Register("gormcache:after_update", p.afterUpdate)
+	if err != nil {
+		return err
+	}
+	return callback.Delete().After("gorm:commit_or_rollback_transaction").Register("gormcache:after_delete", p.afterDelete)
+}
+
+func (p *InvalidationPlugin) queryBeforeImages(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return
+	}
+	daos := findCacheDaos(db.Statement.Schema.ModelType)
+	if len(daos) == 0 {
+		return
+	}
+
+	stmt := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true}).Model(reflect.New(stmt.Schema.ModelType).Interface())
+	if stmt.Unscoped {
+		tx = tx.Unscoped()
+	}
+	conditions := 0
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			tx = tx.Clauses(where)
+			conditions++
+		}
+	}
+	if expr := primaryKeyCondition(stmt.Schema, stmt.ReflectValue); expr != nil {
+		tx = tx.Clauses(clause.Where{Exprs: []clause.Expression{expr}})
+		conditions++
+	}
+	if conditions == 0 {
+		// gorm refuses it without 'AllowGlobalUpdate', and it's too expensive to notify the whole table
+		daos[0].logger().Warn("no condition, skip querying rows before modified", log.F("table", stmt.Schema.Table))
+		return
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := tx.Find(rows.Interface()).Error
+	if err != nil {
+		daos[0].logger().Error("query rows before modified failed", log.F("table", stmt.Schema.Table), log.Err(err))
+		return
+	}
+	db.InstanceSet(pluginBeforeImagesKey, rows.Elem())
+}
+
+func (p *InvalidationPlugin) afterCreate(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return
+	}
+	notifyRows(db, db.Statement.Schema, db.Statement.ReflectValue)
+}
+
+func (p *InvalidationPlugin) afterUpdate(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return
+	}
+	beforeImages, ok := db.InstanceGet(pluginBeforeImagesKey)
+	if !ok {
+		// the cached objects are taken as the old rows
+		notifyUpdatedRows(db, db.Statement.Schema, reflect.Value{}, db.Statement.ReflectValue)
+		return
+	}
+
+	// 'Updates' with map or 'UpdateColumn' leaves the model partial, so query the new field values again
+	rows := beforeImages.(reflect.Value)
+	expr := primaryKeyCondition(db.Statement.Schema, rows)
+	if expr == nil {
+		notifyRows(db, db.Statement.Schema, rows)
+		return
+	}
+	afterRows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true}).Model(reflect.New(db.Statement.Schema.ModelType).Interface()).
+		Clauses(clause.Where{Exprs: []clause.Expression{expr}}).Find(afterRows.Interface()).Error
+	if err != nil {
+		if daos := findCacheDaos(db.Statement.Schema.ModelType); len(daos) > 0 {
+			daos[0].logger().Error("query rows after updated failed", log.F("table", db.Statement.Schema.Table), log.Err(err))
+		}
+		notifyRows(db, db.Statement.Schema, rows)
+		notifyRows(db, db.Statement.Schema, db.Statement.ReflectValue)
+		return
+	}
+	notifyUpdatedRows(db, db.Statement.Schema, rows, afterRows.Elem())
+}
+
+func (p *InvalidationPlugin) afterDelete(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return
+	}
+	if beforeImages, ok := db.InstanceGet(pluginBeforeImagesKey); ok {
+		notifyRows(db, db.Statement.Schema, beforeImages.(reflect.Value))
+		return
+	}
+	notifyRows(db, db.Statement.Schema, db.Statement.ReflectValue)
+}
+
+// notifyRows invoke 'NotifyModifiedTx' of daos for each row, rows can be struct or slice of struct
+func notifyRows(db *gorm.DB, sch *schema.Schema, rows reflect.Value) {
+	daos := findCacheDaos(sch.ModelType)
+	if len(daos) == 0 {
+		return
+	}
+
+	forEachRow(rows, sch.ModelType, func(row reflect.Value) {
+		obj := row.Addr().Interface()
+		for _, base := range daos {
+			err := base.NotifyModifiedTx(db, obj)
+			if err != nil {
+				base.logger().Error("notify modified failed", log.Key(base.GetPrimaryKey(obj)), log.Err(err))
+			}
+		}
+	})
+}
+
+// notifyUpdatedRows invoke 'NotifyUpdatedTx' of daos for each pair of rows matched by primary key,
+// if beforeRows is invalid, the cached objects are taken as the old rows.
+func notifyUpdatedRows(db *gorm.DB, sch *schema.Schema, beforeRows, afterRows reflect.Value) {
+	daos := findCacheDaos(sch.ModelType)
+	if len(daos) == 0 {
+		return
+	}
+	if !beforeRows.IsValid() {
+		forEachRow(afterRows, sch.ModelType, func(row reflect.Value) {
+			after := row.Addr().Interface()
+			for _, base := range daos {
+				err := base.NotifyUpdatedTx(db, nil, after)
+				if err != nil {
+					base.logger().Error("notify updated failed", log.Key(base.GetPrimaryKey(after)), log.Err(err))
+				}
+			}
+		})
+		return
+	}
+
+	afterMap := make(map[string]interface{})
+	forEachRow(afterRows, sch.ModelType, func(row reflect.Value) {
+		afterMap[primaryKeyString(sch, row)] = row.Addr().Interface()
+	})
+	forEachRow(beforeRows, sch.ModelType, func(row reflect.Value) {
+		before := row.Addr().Interface()
+		after := afterMap[primaryKeyString(sch, row)]
+		for _, base := range daos {
+			var err error
+			if after == nil {
+				// primary key is updated, or the row is gone
+				err = base.NotifyModifiedTx(db, before)
+			} else {
+				err = base.NotifyUpdatedTx(db, before, after)
+			}
+			if err != nil {
+				base.logger().Error("notify updated failed", log.Key(base.GetPrimaryKey(before)), log.Err(err))
+			}
+		}
+	})
+}
+
+// primaryKeyString join primary key values of row
+func primaryKeyString(sch *schema.Schema, row reflect.Value) string {
+	values := make([]string, 0, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		value, _ := field.ValueOf(row)
+		values = append(values, util.GeneralToString(value))
+	}
+	return strings.Join(values, "_")
+}
+
+// primaryKeyCondition make condition matching the primary keys of rows, nil if no primary key is set
+func primaryKeyCondition(sch *schema.Schema, rows reflect.Value) clause.Expression {
+	if len(sch.PrimaryFields) == 0 {
+		return nil
+	}
+
+	exprs := make([]clause.Expression, 0)
+	values := make([]interface{}, 0)
+	forEachRow(rows, sch.ModelType, func(row reflect.Value) {
+		eqs := make([]clause.Expression, 0, len(sch.PrimaryFields))
+		for _, field := range sch.PrimaryFields {
+			value, zero := field.ValueOf(row)
+			if zero {
+				return
+			}
+			eqs = append(eqs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+			values = append(values, value)
+		}
+		exprs = append(exprs, clause.And(eqs...))
+	})
+	if len(exprs) == 0 {
+		return nil
+	}
+	if len(sch.PrimaryFields) == 1 {
+		return clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: sch.PrimaryFields[0].DBName}, Values: values}
+	}
+	return clause.Or(exprs...)
+}
+
+// forEachRow iterate the struct rows of modelType in rows, the row passed to fn is addressable
+func forEachRow(rows reflect.Value, modelType reflect.Type, fn func(row reflect.Value)) {
+	for rows.IsValid() && (rows.Kind() == reflect.Ptr || rows.Kind() == reflect.Interface) {
+		rows = rows.Elem()
+	}
+	if !rows.IsValid() {
+		return
+	}
+
+	switch rows.Kind() {
+	case reflect.Struct:
+		if rows.Type() != modelType {
+			return
+		}
+		if !rows.CanAddr() {
+			row := reflect.New(modelType).Elem()
+			row.Set(rows)
+			rows = row
+		}
+		fn(rows)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			forEachRow(rows.Index(i), modelType, fn)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newPluginTestDao new cache dao of testUser on redis store, with invalidation plugin used by db
+func newPluginTestDao(t *testing.T, plugin *InvalidationPlugin) (*testUserCacheDao, *gorm.DB) {
+	db := newTestDB(t)
+	if err := db.Use(plugin); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := newTestRedisStore(t)
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.Store = store
+	})
+	return dao, db
+}
+
+// versionKeysOf version keys of the concrete key of name and the range key of status
+func versionKeysOf(dao *testUserCacheDao, names ...string) []string {
+	keys := make([]string, 0)
+	for _, name := range names {
+		info := dao.MethodNotifyInfoMap["GetByName"]
+		keys = append(keys, dao.MakeVersionKey(info.VersionKeyPrefix, info, []string{name}))
+	}
+	info := dao.MethodNotifyInfoMap["GetByStatus"]
+	return append(keys, dao.MakeVersionKey(info.VersionKeyPrefix, info, nil))
+}
+
+// versions get the values of version keys, "" if absent
+func versions(t *testing.T, dao *testUserCacheDao, keys []string) map[string]string {
+	t.Helper()
+	ret := make(map[string]string)
+	for _, key := range keys {
+		item, err := dao.cacheStore().Get(context.Background(), key)
+		if err == ErrCacheMiss {
+			ret[key] = ""
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret[key] = string(item.Value)
+	}
+	return ret
+}
+
+// checkBumped check all the version keys are changed by modify
+func checkBumped(t *testing.T, dao *testUserCacheDao, keys []string, modify func() error) {
+	t.Helper()
+	before := versions(t, dao, keys)
+	if err := modify(); err != nil {
+		t.Fatal(err)
+	}
+	after := versions(t, dao, keys)
+	for _, key := range keys {
+		if after[key] == "" || after[key] == before[key] {
+			t.Errorf("version key %s not bumped: %q -> %q", key, before[key], after[key])
+		}
+	}
+}
+
+func TestPluginCreate(t *testing.T) {
+	dao, db := newPluginTestDao(t, &InvalidationPlugin{QueryImages: true})
+	checkBumped(t, dao, versionKeysOf(dao, "alice"), func() error {
+		return db.Create(&testUser{Name: "alice", Status: 1}).Error
+	})
+	checkBumped(t, dao, versionKeysOf(dao, "bob", "carol"), func() error {
+		return db.Create([]testUser{{Name: "bob", Status: 1}, {Name: "carol", Status: 2}}).Error
+	})
+}
+
+func TestPluginSave(t *testing.T) {
+	dao, db := newPluginTestDao(t, &InvalidationPlugin{QueryImages: true})
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	cacheTestObject(t, dao, user.Id)
+
+	checkBumped(t, dao, versionKeysOf(dao, "alice", "bob"), func() error {
+		return db.Save(&testUser{Id: user.Id, Name: "bob", Status: 2}).Error
+	})
+	if testObjectCached(t, dao, user.Id) {
+		t.Fatal("object cache not invalidated by save")
+	}
+}
+
+func TestPluginUpdatesMap(t *testing.T) {
+	dao, db := newPluginTestDao(t, &InvalidationPlugin{QueryImages: true})
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the model has primary key only, the old and new names are queried
+	checkBumped(t, dao, versionKeysOf(dao, "alice", "bob"), func() error {
+		return db.Model(&testUser{Id: user.Id}).Updates(map[string]interface{}{"name": "bob"}).Error
+	})
+	got, err := dao.GetByName("bob")
+	if err != nil || got == nil || got.Id != user.Id {
+		t.Fatalf("GetByName after update: got %v %v", got, err)
+	}
+	// by conditions without model
+	checkBumped(t, dao, versionKeysOf(dao, "bob", "carol"), func() error {
+		return db.Model(&testUser{}).Where("status = ?", 1).Updates(map[string]interface{}{"name": "carol"}).Error
+	})
+}
+
+func TestPluginBatchDelete(t *testing.T) {
+	dao, db := newPluginTestDao(t, &InvalidationPlugin{QueryImages: true})
+	users := []testUser{{Name: "alice", Status: 1}, {Name: "bob", Status: 1}, {Name: "carol", Status: 2}}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range users {
+		cacheTestObject(t, dao, user.Id)
+	}
+
+	checkBumped(t, dao, versionKeysOf(dao, "alice", "bob"), func() error {
+		return db.Where("status = ?", 1).Delete(&testUser{}).Error
+	})
+	for i, user := range users {
+		if cached := testObjectCached(t, dao, user.Id); cached != (i == 2) {
+			t.Fatalf("object cache of %s: cached %v", user.Name, cached)
+		}
+	}
+}
+
+func TestPluginWithoutImages(t *testing.T) {
+	dao, db := newPluginTestDao(t, NewInvalidationPlugin())
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	queries := 0
+	err := db.Callback().Query().Register("test:count_queries", func(db *gorm.DB) { queries++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached object is taken as the old row
+	cacheTestObject(t, dao, user.Id)
+	queries = 0
+	checkBumped(t, dao, versionKeysOf(dao, "alice", "bob"), func() error {
+		return db.Model(&testUser{Id: user.Id}).Updates(map[string]interface{}{"name": "bob"}).Error
+	})
+	checkBumped(t, dao, versionKeysOf(dao, "bob"), func() error {
+		return db.Delete(&testUser{Id: user.Id, Name: "bob", Status: 1}).Error
+	})
+	if queries != 0 {
+		t.Fatalf("rows queried by plugin without images: %d", queries)
+	}
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CompositeKey values of composite primary key, in the order of the primary fields of Do
+type CompositeKey []interface{}
+
+// resolveSchema resolve table name and primary key fields of Do by gorm schema, with the naming strategy of 'ReadDBSource'.
+// primary key fields are those tagged by 'primaryKey', or the field named 'ID'/'Id'.
+func (base *CacheDaoBase) resolveSchema() error {
+	var sch *schema.Schema
+	var err error
+	if base.ReadDBSource != nil {
+		stmt := &gorm.Statement{DB: base.ReadDBSource}
+		err = stmt.Parse(base.Do)
+		sch = stmt.Schema
+	} else {
+		sch, err = schema.Parse(base.Do, &sync.Map{}, schema.NamingStrategy{})
+	}
+	if err != nil {
+		return err
+	}
+	if len(sch.PrimaryFields) == 0 {
+		return errors.New("do object should have primary key fields, tagged by 'primaryKey' or named 'ID'")
+	}
+	base.schema = sch
+	base.primaryFields = sch.PrimaryFields
+	base.IDFieldName = sch.PrimaryFields[0].Name
+	return nil
+}
+
+// GetPrimaryKey get the primary key of object, CompositeKey if there are multiple primary fields
+func (base *CacheDaoBase) GetPrimaryKey(do interface{}) interface{} {
+	doValue := reflect.ValueOf(do)
+	if len(base.primaryFields) == 1 {
+		value, _ := base.primaryFields[0].ValueOf(doValue)
+		return value
+	}
+	key := make(CompositeKey, 0, len(base.primaryFields))
+	for _, field := range base.primaryFields {
+		value, _ := field.ValueOf(doValue)
+		key = append(key, value)
+	}
+	return key
+}
+
+// normalizeKey check the primary key given by caller, []interface{} is taken as CompositeKey
+func (base *CacheDaoBase) normalizeKey(key interface{}) (interface{}, error) {
+	if util.IsNil(key) {
+		return nil, errors.New("illegal primary key, should not be nil")
+	}
+	if values, ok := key.([]interface{}); ok {
+		key = CompositeKey(values)
+	}
+	values, ok := key.(CompositeKey)
+	if len(base.primaryFields) == 1 {
+		if ok {
+			return nil, fmt.Errorf("illegal primary key %v, %s has single primary field", key, base.ObjectCachePrefix)
+		}
+		return key, nil
+	}
+	if !ok || len(values) != len(base.primaryFields) {
+		return nil, fmt.Errorf("illegal primary key %v, should be CompositeKey of %d values", key, len(base.primaryFields))
+	}
+	return key, nil
+}
+
+// getPrimaryKeys get primary keys from do list
+func (base *CacheDaoBase) getPrimaryKeys(doList interface{}) ([]interface{}, error) {
+	if util.IsNil(doList) {
+		return []interface{}{}, nil
+	}
+	if !util.RealTypeCheck(doList, reflect.Slice) {
+		return nil, errors.New("value type is not slice")
+	}
+	_, doListValue := util.GetRealTypeAndValue(doList)
+	ret := make([]interface{}, 0, doListValue.Len())
+	for i := 0; i < doListValue.Len(); i++ {
+		ret = append(ret, base.GetPrimaryKey(doListValue.Index(i).Interface()))
+	}
+	return ret, nil
+}
+
+// encodeKey encode primary key to the string used in cache keys,
+// each value is escaped, so the result is unique without resolving it back.
+func (base *CacheDaoBase) encodeKey(key interface{}) string {
+	if values, ok := key.(CompositeKey); ok {
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			strs = append(strs, url.QueryEscape(util.GeneralToString(v)))
+		}
+		return strings.Join(strs, ",")
+	}
+	return url.QueryEscape(util.GeneralToString(key))
+}
+
+// marshalKey marshal primary key to the value cached for key queries
+func (base *CacheDaoBase) marshalKey(key interface{}) ([]byte, error) {
+	return json.Marshal(key)
+}
+
+// unmarshalKey unmarshal primary key to the types of primary fields
+func (base *CacheDaoBase) unmarshalKey(data []byte) (interface{}, error) {
+	if len(base.primaryFields) == 1 {
+		return unmarshalFieldValue(data, base.primaryFields[0])
+	}
+	raws := make([]json.RawMessage, 0)
+	err := json.Unmarshal(data, &raws)
+	if err != nil {
+		return nil, err
+	}
+	if len(raws) != len(base.primaryFields) {
+		return nil, fmt.Errorf("composite key %s doesn't match %d primary fields", string(data), len(base.primaryFields))
+	}
+	key := make(CompositeKey, 0, len(raws))
+	for i := range raws {
+		value, err := unmarshalFieldValue(raws[i], base.primaryFields[i])
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, value)
+	}
+	return key, nil
+}
+
+// unmarshalKeys unmarshal primary key list cached for range queries
+func (base *CacheDaoBase) unmarshalKeys(data []byte) ([]interface{}, error) {
+	raws := make([]json.RawMessage, 0)
+	err := json.Unmarshal(data, &raws)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, 0, len(raws))
+	for i := range raws {
+		key, err := base.unmarshalKey(raws[i])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func unmarshalFieldValue(data []byte, field *schema.Field) (interface{}, error) {
+	value := reflect.New(field.FieldType)
+	err := json.Unmarshal(data, value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
+
+// primaryKeysCondition make condition matching the primary keys
+func (base *CacheDaoBase) primaryKeysCondition(keys []interface{}) clause.Expression {
+	if len(base.primaryFields) == 1 {
+		column := clause.Column{Table: clause.CurrentTable, Name: base.primaryFields[0].DBName}
+		if len(keys) == 1 {
+			return clause.Eq{Column: column, Value: keys[0]}
+		}
+		return clause.IN{Column: column, Values: keys}
+	}
+
+	exprs := make([]clause.Expression, 0, len(keys))
+	for _, key := range keys {
+		values, ok := key.(CompositeKey)
+		if !ok || len(values) != len(base.primaryFields) {
+			continue
+		}
+		eqs := make([]clause.Expression, 0, len(values))
+		for i, field := range base.primaryFields {
+			eqs = append(eqs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: values[i]})
+		}
+		exprs = append(exprs, clause.And(eqs...))
+	}
+	if len(exprs) == 0 {
+		// never match, rather than the whole table
+		return clause.Expr{SQL: "1 <> 1"}
+	}
+	return clause.Or(exprs...)
+}
+
+// idsToKeys convert integer ids to primary keys
+func idsToKeys(ids []uint64) []interface{} {
+	keys := make([]interface{}, 0, len(ids))
+	for i := range ids {
+		keys = append(keys, ids[i])
+	}
+	return keys
+}
+
+// primaryColumns db column names of primary fields
+func (base *CacheDaoBase) primaryColumns() []string {
+	columns := make([]string, 0, len(base.primaryFields))
+	for _, field := range base.primaryFields {
+		columns = append(columns, field.DBName)
+	}
+	return columns
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// testDoc Do of string primary key
+type testDoc struct {
+	Code  string `gorm:"primaryKey"`
+	Title string
+}
+
+// testGrant Do of composite primary key of strings
+type testGrant struct {
+	Scope   string `gorm:"primaryKey"`
+	Subject string `gorm:"primaryKey"`
+	Level   int
+}
+
+// testPlainSQLDao sql dao without cached methods
+type testPlainSQLDao struct {
+	db *gorm.DB
+}
+
+func (d *testPlainSQLDao) GetReadDbSource() *gorm.DB {
+	return d.db
+}
+
+// testPlainCacheDao cache dao without notify declarations
+type testPlainCacheDao struct {
+	CacheDaoBase
+}
+
+// newPlainTestCacheDao new initialized cache dao of do, whose table is migrated in db
+func newPlainTestCacheDao(t *testing.T, db *gorm.DB, do interface{}, setup func(dao *testPlainCacheDao)) *testPlainCacheDao {
+	if err := db.AutoMigrate(do); err != nil {
+		t.Fatal(err)
+	}
+	dao := &testPlainCacheDao{}
+	dao.Do = do
+	dao.SQLDao = &testPlainSQLDao{db: db}
+	dao.Store = newMemStore()
+	if setup != nil {
+		setup(dao)
+	}
+	if err := dao.Initialize(dao); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dao.Close() })
+	return dao
+}
+
+func TestStringPrimaryKeys(t *testing.T) {
+	db := newTestDB(t)
+	// keys with the separators of cache keys and their escaped forms
+	docs := []testDoc{{"a,b", "comma"}, {"a_b", "underscore"}, {"a%2Cb", "escaped comma"}, {"a b", "space"}, {"x/y?z=1", "url"}}
+	if err := db.AutoMigrate(&testDoc{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&docs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, localCache := range []bool{false, true} {
+		dao := newPlainTestCacheDao(t, db, &testDoc{}, func(dao *testPlainCacheDao) {
+			if localCache {
+				dao.LocalCache = &LocalCacheConfig{ExpireMillis: 60000}
+			}
+		})
+
+		encoded := make(map[string]string)
+		for _, doc := range docs {
+			key := dao.encodeKey(doc.Code)
+			if code, ok := encoded[key]; ok {
+				t.Fatalf("keys %q and %q are both encoded as %q", code, doc.Code, key)
+			}
+			encoded[key] = doc.Code
+		}
+
+		// loaded from sql, then cached partly, then all cached
+		keys := []string{"a%2Cb", "absent", "a,b", "x/y?z=1", "a_b", "a b"}
+		want := []string{"a%2Cb", "a,b", "x/y?z=1", "a_b", "a b"}
+		for _, prefetch := range [][]string{nil, {"a,b", "a b"}, nil} {
+			if prefetch != nil {
+				invalidateDocs(t, dao, keys)
+				for _, code := range prefetch {
+					if _, err := dao.GetByPrimaryKey(code); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			list, err := ToList[testDoc](dao.GetByPrimaryKeys(keys))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDocCodes(t, list, want)
+			for _, doc := range list {
+				if doc.Title == "" {
+					t.Fatalf("doc %q not loaded", doc.Code)
+				}
+			}
+		}
+	}
+}
+
+// invalidateDocs invalidate the object caches of docs
+func invalidateDocs(t *testing.T, dao *testPlainCacheDao, codes []string) {
+	t.Helper()
+	for _, code := range codes {
+		if err := dao.NotifyModified(&testDoc{Code: code}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkDocCodes(t *testing.T, list []testDoc, want []string) {
+	t.Helper()
+	codes := make([]string, 0, len(list))
+	for _, doc := range list {
+		codes = append(codes, doc.Code)
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("codes of docs: got %q, want %q", codes, want)
+	}
+}
+
+func TestCompositePrimaryKeys(t *testing.T) {
+	db := newTestDB(t)
+	dao := newPlainTestCacheDao(t, db, &testGrant{}, nil)
+	// the joined values would collide without escaping
+	grants := []testGrant{{"a,b", "c", 1}, {"a", "b,c", 2}, {"a_b", "c", 3}, {"a", "b_c", 4}}
+	if err := db.Create(&grants).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := make(map[string]bool)
+	for _, grant := range grants {
+		key := dao.encodeKey(dao.GetPrimaryKey(&grant))
+		if encoded[key] {
+			t.Fatalf("composite key of %v collides: %q", grant, key)
+		}
+		encoded[key] = true
+	}
+
+	keys := []interface{}{
+		CompositeKey{"a", "b_c"},
+		[]interface{}{"a,b", "c"}, // taken as CompositeKey
+		CompositeKey{"a", "absent"},
+		CompositeKey{"a", "b,c"},
+		CompositeKey{"a_b", "c"},
+	}
+	for i := 0; i < 2; i++ {
+		list, err := ToList[testGrant](dao.GetByPrimaryKeys(keys))
+		if err != nil {
+			t.Fatal(err)
+		}
+		levels := make([]int, 0)
+		for _, grant := range list {
+			levels = append(levels, grant.Level)
+		}
+		if !reflect.DeepEqual(levels, []int{4, 1, 2, 3}) {
+			t.Fatalf("levels of grants (cached %v): got %v, want [4 1 2 3]", i > 0, levels)
+		}
+	}
+
+	grant, err := ToObject[testGrant](dao.GetByPrimaryKey(CompositeKey{"a", "b,c"}))
+	if err != nil || grant == nil || grant.Level != 2 {
+		t.Fatalf("GetByPrimaryKey: got %v %v", grant, err)
+	}
+	if err := db.Model(grant).Update("level", 5).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.NotifyModified(grant); err != nil {
+		t.Fatal(err)
+	}
+	grant, err = ToObject[testGrant](dao.GetByPrimaryKey(CompositeKey{"a", "b,c"}))
+	if err != nil || grant == nil || grant.Level != 5 {
+		t.Fatalf("GetByPrimaryKey after notified: got %v %v", grant, err)
+	}
+
+	// illegal keys
+	for _, key := range []interface{}{"a", CompositeKey{"a"}, nil} {
+		if _, err := dao.GetByPrimaryKey(key); err == nil {
+			t.Fatalf("GetByPrimaryKey %v should fail", key)
+		}
+	}
+	if expr := dao.primaryKeysCondition([]interface{}{"a"}); !reflect.DeepEqual(expr, clause.Expr{SQL: "1 <> 1"}) {
+		t.Fatalf("condition of no legal composite key: got %v", expr)
+	}
+}
+
+// testRenamedDoc Do whose primary column is renamed by tag
+type testRenamedDoc struct {
+	Code  string `gorm:"column:doc_code;primaryKey"`
+	Title string `gorm:"column:doc_title"`
+}
+
+// testOrderLine Do with table name overridden and composite primary key with renamed column
+type testOrderLine struct {
+	ID     uint64 `gorm:"primaryKey;autoIncrement:false"`
+	LineNo int    `gorm:"primaryKey;column:line"`
+	Amount int
+}
+
+func (testOrderLine) TableName() string {
+	return "order_lines"
+}
+
+// testNoKey Do without primary key
+type testNoKey struct {
+	Name string
+}
+
+func TestResolveSchema(t *testing.T) {
+	db := newTestDBWithConfig(t, &gorm.Config{NamingStrategy: schema.NamingStrategy{TablePrefix: "app_", SingularTable: true}})
+	cases := []struct {
+		do      interface{}
+		table   string
+		columns []string
+		idField string
+	}{
+		{&testUser{}, "app_test_user", []string{"id"}, "Id"},
+		{&testRenamedDoc{}, "app_test_renamed_doc", []string{"doc_code"}, "Code"},
+		{&testOrderLine{}, "order_lines", []string{"id", "line"}, "ID"},
+	}
+	for _, c := range cases {
+		dao := newPlainTestCacheDao(t, db, c.do, nil)
+		if dao.schema.Table != c.table || !reflect.DeepEqual(dao.primaryColumns(), c.columns) || dao.IDFieldName != c.idField {
+			t.Fatalf("schema of %T: got table %q, primary columns %q, id field %q", c.do, dao.schema.Table, dao.primaryColumns(), dao.IDFieldName)
+		}
+	}
+
+	// objects are loaded by the resolved table and columns
+	dao := newPlainTestCacheDao(t, db, &testRenamedDoc{}, nil)
+	if err := db.Create(&testRenamedDoc{Code: "c1", Title: "renamed"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ToObject[testRenamedDoc](dao.GetByPrimaryKey("c1"))
+	if err != nil || doc == nil || doc.Title != "renamed" {
+		t.Fatalf("GetByPrimaryKey of renamed column: got %v %v", doc, err)
+	}
+	lines := newPlainTestCacheDao(t, db, &testOrderLine{}, nil)
+	if err := db.Create(&testOrderLine{ID: 1, LineNo: 2, Amount: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+	line, err := ToObject[testOrderLine](lines.GetByPrimaryKey(CompositeKey{uint64(1), 2}))
+	if err != nil || line == nil || line.Amount != 3 {
+		t.Fatalf("GetByPrimaryKey of overridden table: got %v %v", line, err)
+	}
+
+	noKey := &testPlainCacheDao{}
+	noKey.Do = &testNoKey{}
+	noKey.SQLDao = &testPlainSQLDao{db: db}
+	noKey.Store = newMemStore()
+	if err := noKey.Initialize(noKey); err == nil {
+		t.Fatal("Initialize of Do without primary key should fail")
+	}
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/gorm"
+)
+
+// default max primary key count stored by paged range cache
+const defaultRangePageMaxIds = 1000
+
+// rangePageCache cached value of paged range
+type rangePageCache struct {
+	Keys  json.RawMessage `json:"keys"`  // ordered primary keys, at most 'RangePageMaxIds'
+	Total int64           `json:"total"` // count of the whole range
+}
+
+// setting key of 'gorm.DB', the sql of range to count is captured into its value by 'captureRangeSQL'
+const rangeSQLCaptureKey = "gormcache:range_sql_capture"
+
+// name of the query callback capturing the sql of range
+const rangeSQLCaptureCallback = "gormcache:capture_range_sql"
+
+// rangeSQLCapture sql and vars of the last query built on the db with it as setting
+type rangeSQLCapture struct {
+	sql  string
+	vars []interface{}
+}
+
+// registerRangeSQLCapture register the query callback capturing the sql of range on db, once per db
+func registerRangeSQLCapture(db *gorm.DB) error {
+	if db == nil || db.Callback().Query().Get(rangeSQLCaptureCallback) != nil {
+		return nil
+	}
+	return db.Callback().Query().After("gorm:query").Register(rangeSQLCaptureCallback, captureRangeSQL)
+}
+
+// captureRangeSQL capture the sql built by the query, the settings of statement are kept by the clones of db
+// (like 'WithContext', 'Session' and scopes), so the query is seen however the sql dao method chains the arg.
+func captureRangeSQL(db *gorm.DB) {
+	v, ok := db.Get(rangeSQLCaptureKey)
+	if !ok || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	if capture, ok := v.(*rangeSQLCapture); ok {
+		capture.sql = db.Statement.SQL.String()
+		capture.vars = append([]interface{}{}, db.Statement.Vars...)
+	}
+}
+
+// rangePage ordered primary keys (maybe capped) and total count of a range
+type rangePage struct {
+	keys  []interface{}
+	total int64
+}
+
+// GetByRangePage paged range cache, the ordered primary keys of range are cached once per version,
+// and the page of [offset, offset+limit) is sliced from them. return the page and the total count.
+// only the first 'RangePageMaxIds' keys are cached, the pages beyond are loaded from sql.
+// the caller should be named as the sql dao method, use 'CachedMethod.GetPage' if the name is taken by 'GetByRange' wrapper.
+func (base *CacheDaoBase) GetByRangePage(offset, limit int, args ...interface{}) (interface{}, int64, error) {
+	return base.getByRangePage(context.Background(), util.GetLastExecuteFuncName(), offset, limit, args...)
+}
+
+// GetByRangePageContext paged range cache, with context
+func (base *CacheDaoBase) GetByRangePageContext(ctx context.Context, offset, limit int, args ...interface{}) (interface{}, int64, error) {
+	return base.getByRangePage(ctx, util.GetLastExecuteFuncName(), offset, limit, args...)
+}
+
+func (base *CacheDaoBase) getByRangePage(ctx context.Context, sqlMethodName string, offset, limit int, args ...interface{}) (ret interface{}, total int64, err error) {
+	if offset < 0 || limit <= 0 {
+		return nil, 0, errors.New("illegal page, offset should >= 0 and limit should > 0")
+	}
+	ctx, span := base.startSpan(ctx, "GetByRangePage", attrMethod.String(sqlMethodName), attrOffset.Int(offset), attrLimit.Int(limit))
+	defer span.end(&err)
+	span.keys(1)
+	defer base.stats(sqlMethodName).since(time.Now())
+
+	page, err := base.getRangePage(ctx, sqlMethodName, span, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(offset) >= page.total {
+		return base.makeObjListPtr(), page.total, nil
+	}
+
+	end := offset + limit
+	if end <= len(page.keys) || int64(len(page.keys)) == page.total {
+		if end > len(page.keys) {
+			end = len(page.keys)
+		}
+		list, err := base.getByPrimaryKeys(ctx, page.keys[offset:end])
+		return list, page.total, err
+	}
+
+	// beyond the cached keys
+	base.logger().Debug("GetByRangePage page beyond cached keys", log.Method(sqlMethodName), log.Args(args), log.F("offset", offset), log.F("limit", limit))
+	keys, err := base.sqlGetRangeKeys(ctx, sqlMethodName, offset, limit, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	list, err := base.getByPrimaryKeys(ctx, keys)
+	return list, page.total, err
+}
+
+// getRangePage get range page from cache, if absent, load it from sql
+func (base *CacheDaoBase) getRangePage(ctx context.Context, sqlMethodName string, span *phaseSpan, args ...interface{}) (*rangePage, error) {
+	stats := base.stats(sqlMethodName).traced(span)
+	version, err := base.GetVersionContext(ctx, sqlMethodName, args...)
+	if err != nil || version == "" {
+		base.logMiss("GetByRangePage missed version", err, log.Method(sqlMethodName), log.Args(args))
+		stats.versionMiss(1)
+		return base.loadRangePage(ctx, sqlMethodName, args...)
+	}
+
+	cacheKey := base.MakeKey(base.makeRangePageKeyPrefix(sqlMethodName, args...), version)
+	cacheItem, err := base.getMethodCache(ctx, sqlMethodName, cacheKey)
+	if err != nil {
+		base.logMiss("GetByRangePage missed cache", err, log.Method(sqlMethodName), log.Args(args))
+		stats.miss(1)
+		return base.loadRangePage(ctx, sqlMethodName, args...)
+	}
+
+	base.logger().Debug("GetByRangePage hit cache", log.Method(sqlMethodName), log.Key(cacheKey))
+	stats.hit(1)
+	if isNegativeValue(cacheItem.Value) {
+		return &rangePage{keys: []interface{}{}}, nil
+	}
+	cache := &rangePageCache{}
+	err = json.Unmarshal(cacheItem.Value, cache)
+	if err != nil {
+		stats.deserializeFailure(1)
+		return nil, err
+	}
+	keys, err := base.unmarshalKeys(cache.Keys)
+	if err != nil {
+		stats.deserializeFailure(1)
+		return nil, err
+	}
+	return &rangePage{keys: keys, total: cache.Total}, nil
+}
+
+// loadRangePage load the first 'RangePageMaxIds' primary keys of range from sql, and cache them with total count,
+// the total is counted by sql only if the range has more keys.
+func (base *CacheDaoBase) loadRangePage(ctx context.Context, sqlMethodName string, args ...interface{}) (*rangePage, error) {
+	keys, err := base.sqlGetRangeKeys(ctx, sqlMethodName, 0, base.RangePageMaxIds, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &rangePage{keys: keys, total: int64(len(keys))}
+	if len(keys) >= base.RangePageMaxIds {
+		page.total, err = base.sqlCountRange(ctx, sqlMethodName, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = base.setRangePage(ctx, page, sqlMethodName, args...)
+	if err != nil {
+		base.logger().Error("GetByRangePage set cache failed", log.Method(sqlMethodName), log.Args(args), log.Err(err))
+	}
+	return page, nil
+}
+
+func (base *CacheDaoBase) setRangePage(ctx context.Context, page *rangePage, sqlMethodName string, args ...interface{}) error {
+	keyPrefix := base.makeRangePageKeyPrefix(sqlMethodName, args...)
+
+	// empty range is cached for a short time as absent
+	if page.total == 0 && base.negativeCacheEnabled() {
+		return base.setMethodCache(ctx, keyPrefix, negativeValue, base.NegativeExpireTime, sqlMethodName, args...)
+	}
+
+	keysJSON, err := json.Marshal(page.keys)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&rangePageCache{Keys: keysJSON, Total: page.total})
+	if err != nil {
+		return err
+	}
+	return base.setMethodCache(ctx, keyPrefix, data, base.ExpireTime, sqlMethodName, args...)
+}
+
+// sqlGetRangeKeys get the primary keys of range from sql dao, the whole range if limit is 0
+func (base *CacheDaoBase) sqlGetRangeKeys(ctx context.Context, sqlMethodName string, offset, limit int, args ...interface{}) ([]interface{}, error) {
+	err := base.dbArgCheck(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// replace the first arg (we assume it's gorm.DB) with Select of primary key columns
+	copyArgs := make([]interface{}, len(args))
+	for i := range args {
+		copyArgs[i] = args[i]
+	}
+	db := base.ReadDBSource.WithContext(ctx).Select(base.primaryColumns())
+	if limit > 0 {
+		db = db.Offset(offset).Limit(limit)
+	}
+	copyArgs[0] = db
+	objs, err := base.invokeSQLDao(ctx, sqlMethodName, copyArgs...)
+	if err != nil {
+		return nil, err
+	}
+	return base.getPrimaryKeys(objs)
+}
+
+// sqlCountRange count the range of sql dao method. the method is invoked in dry run mode to capture its sql,
+// which is counted as subquery, so the conditions and joins of method are kept.
+// if no sql is captured (the method doesn't query on the 'gorm.DB' arg, or drops its settings by 'NewDB'),
+// the range is counted by loading all its keys, which is logged as error since the page cap is defeated.
+func (base *CacheDaoBase) sqlCountRange(ctx context.Context, sqlMethodName string, args ...interface{}) (int64, error) {
+	err := base.dbArgCheck(args...)
+	if err != nil {
+		return 0, err
+	}
+
+	copyArgs := make([]interface{}, len(args))
+	for i := range args {
+		copyArgs[i] = args[i]
+	}
+	capture := &rangeSQLCapture{}
+	copyArgs[0] = base.ReadDBSource.Session(&gorm.Session{DryRun: true, Context: ctx}).
+		Set(rangeSQLCaptureKey, capture).Select(base.primaryColumns())
+	_, err = sqlDaoResult(util.ReflectInvokeMethod(base.SQLDao, sqlMethodName, copyArgs...))
+	if err != nil {
+		return 0, err
+	}
+	if capture.sql == "" {
+		base.logger().Error("can't count range by sql, count by loading all keys, the method should query on the 'gorm.DB' arg",
+			log.Method(sqlMethodName), log.Args(args))
+		keys, err := base.sqlGetRangeKeys(ctx, sqlMethodName, 0, 0, args...)
+		return int64(len(keys)), err
+	}
+
+	var total int64
+	err = base.ReadDBSource.WithContext(ctx).Raw("SELECT COUNT(*) FROM ("+capture.sql+") gormcache_range", capture.vars...).Scan(&total).Error
+	return total, err
+}
+
+// makeRangePageKeyPrefix make key prefix of paged range, differs from 'GetByRange' of the same args
+func (base *CacheDaoBase) makeRangePageKeyPrefix(methodName string, args ...interface{}) string {
+	return base.MakeKeyPrefix(methodName, args...) + "_page"
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"gorm.io/gorm"
+)
+
+// GetByStatusScoped range method chaining the arg by 'WithContext', 'Session' and scopes
+func (d *testUserSQLDao) GetByStatusScoped(db *gorm.DB, status int) ([]testUser, error) {
+	users := make([]testUser, 0)
+	err := db.WithContext(context.Background()).Session(&gorm.Session{}).Scopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", status)
+	}).Order("id").Find(&users).Error
+	return users, err
+}
+
+// GetByStatusIgnoringArg range method querying without the arg, which can't be counted by sql
+func (d *testUserSQLDao) GetByStatusIgnoringArg(db *gorm.DB, status int) ([]testUser, error) {
+	users := make([]testUser, 0)
+	err := d.db.Where("status = ?", status).Order("id").Find(&users).Error
+	return users, err
+}
+
+// newRangePageTestDao new dao with range page capped by 3 keys, the range methods of testUserSQLDao are bound,
+// and 5 users of status 1 are created.
+func newRangePageTestDao(t *testing.T) (*testUserCacheDao, *gorm.DB, []testUser, *recordLogger) {
+	db := newTestDB(t)
+	users := make([]testUser, 0)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		users = append(users, testUser{Name: name, Status: 1})
+	}
+	db.Create(&testUser{Name: "other", Status: 2})
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &recordLogger{}
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.RangePageMaxIds = 3
+		dao.Logger = logger
+		for _, method := range []interface{}{(*testUserSQLDao).GetByStatusScoped, (*testUserSQLDao).GetByStatusIgnoringArg} {
+			dao.MustBindMethod(&MethodBinding{Method: method, Type: constant.NotifyTypeRange, Keys: []string{"Status"}, Args: []int{1}})
+		}
+	})
+	return dao, db, users, logger
+}
+
+func TestGetByRangePage(t *testing.T) {
+	dao, db, users, logger := newRangePageTestDao(t)
+	for _, method := range []string{"GetByStatus", "GetByStatusScoped"} {
+		check := func(offset, limit int, want []testUser) {
+			t.Helper()
+			ret, total, err := dao.getByRangePage(context.Background(), method, offset, limit, db, 1)
+			list, err := ToList[testUser](ret, err)
+			if err != nil || total != int64(len(users)) || len(list) != len(want) {
+				t.Fatalf("%s page [%d, %d): got %v %d %v", method, offset, offset+limit, list, total, err)
+			}
+			for i := range want {
+				if list[i].Id != want[i].Id {
+					t.Fatalf("%s page [%d, %d): got %v, want %v", method, offset, offset+limit, list, want)
+				}
+			}
+		}
+		// the first 3 keys are cached, the total is counted by sql
+		check(0, 2, users[:2])
+		check(2, 2, users[2:4])
+		check(4, 2, users[4:])
+		check(5, 2, nil)
+	}
+	if logger.logged("can't count range by sql") {
+		t.Fatal("range counted by loading all keys")
+	}
+
+	// the total is cached with keys
+	db.Create(&testUser{Name: "f", Status: 1})
+	_, total, err := dao.getByRangePage(context.Background(), "GetByStatus", 0, 1, db, 1)
+	if err != nil || total != int64(len(users)) {
+		t.Fatalf("cached total: got %d %v", total, err)
+	}
+	if err := dao.NotifyModified(&testUser{Name: "f", Status: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, total, err = dao.getByRangePage(context.Background(), "GetByStatus", 0, 1, db, 1)
+	if err != nil || total != int64(len(users)+1) {
+		t.Fatalf("total after notified: got %d %v", total, err)
+	}
+}
+
+func TestGetByRangePageCountFallback(t *testing.T) {
+	dao, db, users, logger := newRangePageTestDao(t)
+	ret, total, err := dao.getByRangePage(context.Background(), "GetByStatusIgnoringArg", 3, 2, db, 1)
+	list, err := ToList[testUser](ret, err)
+	if err != nil || total != int64(len(users)) || len(list) != 2 || list[0].Id != users[3].Id {
+		t.Fatalf("page of fallback: got %v %d %v", list, total, err)
+	}
+	if !logger.logged("can't count range by sql") {
+		t.Fatal("fallback of counting range is not logged")
+	}
+}
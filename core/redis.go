@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// default key count of each MGET command in GetMulti pipeline
+const defaultRedisBatchSize = 500
+
+var redisCASScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+var redisIncrScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+// RedisStore CacheStore implementation based on go-redis,
+// client can be a single node, sentinel or cluster client (or miniredis for test).
+type RedisStore struct {
+	Client    redis.UniversalClient
+	BatchSize int // key count of each MGET in GetMulti, default 500
+}
+
+// NewRedisStore new redis store with client
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{Client: client, BatchSize: defaultRedisBatchSize}
+}
+
+// Get get item by key
+func (s *RedisStore) Get(ctx context.Context, key string) (*Item, error) {
+	val, err := s.Client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return &Item{Key: key, Value: val, casToken: val}, nil
+}
+
+// GetMulti get items by keys with pipelined MGET
+func (s *RedisStore) GetMulti(ctx context.Context, keys []string) (map[string]*Item, error) {
+	ret := make(map[string]*Item, len(keys))
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	// keys of one MGET must be in the same slot in cluster mode, so get them one by one
+	if _, ok := s.Client.(*redis.ClusterClient); ok {
+		return s.getMultiByPipelinedGet(ctx, keys)
+	}
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRedisBatchSize
+	}
+	batches := make([][]string, 0)
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batches = append(batches, keys[start:end])
+	}
+
+	cmds := make([]*redis.SliceCmd, len(batches))
+	_, err := s.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range batches {
+			cmds[i] = pipe.MGet(ctx, batches[i]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	for i := range cmds {
+		for j, v := range cmds[i].Val() {
+			str, ok := v.(string)
+			if !ok {
+				continue
+			}
+			key := batches[i][j]
+			ret[key] = &Item{Key: key, Value: []byte(str), casToken: []byte(str)}
+		}
+	}
+	return ret, nil
+}
+
+func (s *RedisStore) getMultiByPipelinedGet(ctx context.Context, keys []string) (map[string]*Item, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := s.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range keys {
+			cmds[i] = pipe.Get(ctx, keys[i])
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*Item, len(keys))
+	for i := range cmds {
+		val, err := cmds[i].Bytes()
+		if err != nil {
+			continue
+		}
+		ret[keys[i]] = &Item{Key: keys[i], Value: val, casToken: val}
+	}
+	return ret, nil
+}
+
+// Set set item
+func (s *RedisStore) Set(ctx context.Context, item *Item) error {
+	return s.Client.Set(ctx, item.Key, item.Value, redisExpiration(item.Expiration)).Err()
+}
+
+// Add add item with SET NX
+func (s *RedisStore) Add(ctx context.Context, item *Item) error {
+	ok, err := s.Client.SetNX(ctx, item.Key, item.Value, redisExpiration(item.Expiration)).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotStored
+	}
+	return nil
+}
+
+// Delete delete item by key
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	n, err := s.Client.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// CAS set item only if its value is still the one we got
+func (s *RedisStore) CAS(ctx context.Context, item *Item) error {
+	old, ok := item.casToken.([]byte)
+	if !ok {
+		return ErrCacheMiss
+	}
+	ret, err := redisCASScript.Run(ctx, s.Client, []string{item.Key}, old, item.Value, item.Expiration).Int()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	if ret == 0 {
+		return ErrCASConflict
+	}
+	return nil
+}
+
+// Incr increase the value of key
+func (s *RedisStore) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	val, err := redisIncrScript.Run(ctx, s.Client, []string{key}, delta).Int64()
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	return uint64(val), nil
+}
+
+func redisExpiration(seconds int32) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func convertRedisError(err error) error {
+	if err == redis.Nil {
+		return ErrCacheMiss
+	}
+	return err
+}
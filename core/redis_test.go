@@ -0,0 +1,253 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client), mr
+}
+
+func TestRedisStoreMiss(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "absent"); err != ErrCacheMiss {
+		t.Fatalf("Get absent key: got %v, want ErrCacheMiss", err)
+	}
+	if err := store.Delete(ctx, "absent"); err != ErrCacheMiss {
+		t.Fatalf("Delete absent key: got %v, want ErrCacheMiss", err)
+	}
+	if _, err := store.Incr(ctx, "absent", 1); err != ErrCacheMiss {
+		t.Fatalf("Incr absent key: got %v, want ErrCacheMiss", err)
+	}
+	items, err := store.GetMulti(ctx, []string{"absent"})
+	if err != nil || len(items) != 0 {
+		t.Fatalf("GetMulti absent key: got %v %v, want empty", items, err)
+	}
+}
+
+func TestRedisStoreAdd(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if err := store.Add(ctx, &Item{Key: "k", Value: []byte("v1")}); err != nil {
+		t.Fatalf("Add absent key: %v", err)
+	}
+	if err := store.Add(ctx, &Item{Key: "k", Value: []byte("v2")}); err != ErrNotStored {
+		t.Fatalf("Add existing key: got %v, want ErrNotStored", err)
+	}
+	if v, _ := mr.Get("k"); v != "v1" {
+		t.Fatalf("value overwritten by Add: %q", v)
+	}
+}
+
+func TestRedisStoreCAS(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if err := store.CAS(ctx, &Item{Key: "k", Value: []byte("v")}); err != ErrCacheMiss {
+		t.Fatalf("CAS item not got: got %v, want ErrCacheMiss", err)
+	}
+
+	mr.Set("k", "v1")
+	item, err := store.Get(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("k", "v2")
+	item.Value = []byte("v3")
+	if err := store.CAS(ctx, item); err != ErrCASConflict {
+		t.Fatalf("CAS modified item: got %v, want ErrCASConflict", err)
+	}
+	if v, _ := mr.Get("k"); v != "v2" {
+		t.Fatalf("value overwritten by conflicted CAS: %q", v)
+	}
+
+	item, err = store.Get(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Value = []byte("v3")
+	item.Expiration = 10
+	if err := store.CAS(ctx, item); err != nil {
+		t.Fatalf("CAS unmodified item: %v", err)
+	}
+	if v, _ := mr.Get("k"); v != "v3" {
+		t.Fatalf("value not swapped: %q", v)
+	}
+	if ttl := mr.TTL("k"); ttl != 10*time.Second {
+		t.Fatalf("ttl after CAS: got %v, want 10s", ttl)
+	}
+
+	mr.Del("k")
+	if err := store.CAS(ctx, item); err != ErrCASConflict {
+		t.Fatalf("CAS deleted item: got %v, want ErrCASConflict", err)
+	}
+	if mr.Exists("k") {
+		t.Fatal("deleted item created by CAS")
+	}
+}
+
+func TestRedisStoreIncr(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	ctx := context.Background()
+
+	mr.Set("k", "10")
+	val, err := store.Incr(ctx, "k", 5)
+	if err != nil || val != 15 {
+		t.Fatalf("Incr: got %d %v, want 15", val, err)
+	}
+	if _, err := store.Incr(ctx, "absent", 1); err != ErrCacheMiss {
+		t.Fatalf("Incr absent key: got %v, want ErrCacheMiss", err)
+	}
+	if mr.Exists("absent") {
+		t.Fatal("absent key created by Incr")
+	}
+}
+
+func TestRedisStoreExpiration(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, &Item{Key: "expiring", Value: []byte("v"), Expiration: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(ctx, &Item{Key: "added", Value: []byte("v"), Expiration: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, &Item{Key: "persistent", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("expiring"); ttl != 10*time.Second {
+		t.Fatalf("ttl of Set: got %v, want 10s", ttl)
+	}
+	if ttl := mr.TTL("added"); ttl != 20*time.Second {
+		t.Fatalf("ttl of Add: got %v, want 20s", ttl)
+	}
+	if ttl := mr.TTL("persistent"); ttl != 0 {
+		t.Fatalf("ttl without expiration: got %v, want none", ttl)
+	}
+
+	mr.FastForward(11 * time.Second)
+	if _, err := store.Get(ctx, "expiring"); err != ErrCacheMiss {
+		t.Fatalf("Get expired key: got %v, want ErrCacheMiss", err)
+	}
+	if _, err := store.Get(ctx, "added"); err != nil {
+		t.Fatalf("Get unexpired key: %v", err)
+	}
+	if _, err := store.Get(ctx, "persistent"); err != nil {
+		t.Fatalf("Get persistent key: %v", err)
+	}
+}
+
+func TestRedisStoreGetMulti(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	store.BatchSize = 2
+	ctx := context.Background()
+
+	keys := setTestRedisKeys(t, mr)
+	recorder := &pipelineRecorder{}
+	store.Client.AddHook(recorder)
+	items, err := store.GetMulti(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestRedisItems(t, store, items)
+	// 6 keys in MGETs of 2 keys
+	if got := fmt.Sprint(recorder.commands); got != "[mget mget mget]" {
+		t.Fatalf("commands of GetMulti: got %s, want 3 mget", got)
+	}
+
+	items, err = store.GetMulti(ctx, nil)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("GetMulti no keys: got %v %v, want empty", items, err)
+	}
+}
+
+func TestRedisStoreGetMultiCluster(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	keys := setTestRedisKeys(t, mr)
+	recorder := &pipelineRecorder{}
+	client.AddHook(recorder)
+	items, err := store.GetMulti(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestRedisItems(t, store, items)
+	// keys of different slots can't be got by one MGET, so a GET for each key
+	if got := fmt.Sprint(recorder.commands); got != "[get get get get get get]" {
+		t.Fatalf("commands of GetMulti: got %s, want %d get", got, len(keys))
+	}
+}
+
+// setTestRedisKeys set 'k0'..'k4', return them with an absent key
+func setTestRedisKeys(t *testing.T, mr *miniredis.Miniredis) []string {
+	keys := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("k%d", i)
+		if err := mr.Set(key, fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	return append(keys, "absent")
+}
+
+// checkTestRedisItems check the items got of keys set by setTestRedisKeys, and that they can be swapped
+func checkTestRedisItems(t *testing.T, store *RedisStore, items map[string]*Item) {
+	t.Helper()
+	if len(items) != 5 {
+		t.Fatalf("items got: got %d, want 5", len(items))
+	}
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("k%d", i)
+		item, ok := items[key]
+		if !ok || string(item.Value) != fmt.Sprintf("v%d", i) {
+			t.Fatalf("item of %s: got %v", key, item)
+		}
+	}
+	item := items["k0"]
+	item.Value = []byte("swapped")
+	if err := store.CAS(context.Background(), item); err != nil {
+		t.Fatalf("CAS item got by GetMulti: %v", err)
+	}
+}
+
+// pipelineRecorder record the names of pipelined commands
+type pipelineRecorder struct {
+	commands []string
+}
+
+func (r *pipelineRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (r *pipelineRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (r *pipelineRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for i := range cmds {
+		r.commands = append(r.commands, cmds[i].Name())
+	}
+	return ctx, nil
+}
+
+func (r *pipelineRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
@@ -1,6 +1,14 @@
 package core
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
 
 // Serializer serialize interface
 type Serializer interface {
@@ -21,3 +29,82 @@ func (s *JSONSerializer) Serialize(obj interface{}) ([]byte, error) {
 func (s *JSONSerializer) Deserialize(bts []byte, obj interface{}) error {
 	return json.Unmarshal(bts, obj)
 }
+
+// MsgpackSerializer serialize with MessagePack, more compact and faster than json,
+// integers are kept as they are, and time.Time is kept in nanosecond precision (decoded in local time zone).
+type MsgpackSerializer struct {
+}
+
+// Serialize serialize obj
+func (s *MsgpackSerializer) Serialize(obj interface{}) ([]byte, error) {
+	return msgpack.Marshal(obj)
+}
+
+// Deserialize deserialize
+func (s *MsgpackSerializer) Deserialize(bts []byte, obj interface{}) error {
+	return msgpack.Unmarshal(bts, obj)
+}
+
+// GobSerializer serialize with encoding/gob, each value carries its own type definition,
+// so it's larger than msgpack for small objects, but needs no dependency.
+type GobSerializer struct {
+}
+
+// Serialize serialize obj
+func (s *GobSerializer) Serialize(obj interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Deserialize deserialize
+func (s *GobSerializer) Deserialize(bts []byte, obj interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(bts)).Decode(obj)
+}
+
+// ProtobufSerializer serialize with protobuf, for Do types implementing proto.Message.
+// values which are not proto.Message (like the result of 'GetByAggregate') are serialized by 'Fallback', json if nil.
+type ProtobufSerializer struct {
+	Fallback Serializer
+}
+
+// Serialize serialize obj
+func (s *ProtobufSerializer) Serialize(obj interface{}) ([]byte, error) {
+	if msg, ok := protoMessage(obj); ok {
+		return proto.Marshal(msg)
+	}
+	return s.fallback().Serialize(obj)
+}
+
+// Deserialize deserialize
+func (s *ProtobufSerializer) Deserialize(bts []byte, obj interface{}) error {
+	if msg, ok := obj.(proto.Message); ok {
+		return proto.Unmarshal(bts, msg)
+	}
+	return s.fallback().Deserialize(bts, obj)
+}
+
+// protoMessage get obj as proto.Message, the struct value (like the element of Do list) is taken by its pointer
+func protoMessage(obj interface{}) (proto.Message, bool) {
+	if msg, ok := obj.(proto.Message); ok {
+		return msg, true
+	}
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Struct {
+		return nil, false
+	}
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	msg, ok := ptr.Interface().(proto.Message)
+	return msg, ok
+}
+
+func (s *ProtobufSerializer) fallback() Serializer {
+	if s.Fallback != nil {
+		return s.Fallback
+	}
+	return &JSONSerializer{}
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gorm.io/gorm"
+)
+
+// benchUser model with gorm.Model, small columns and a TEXT column
+type benchUser struct {
+	gorm.Model
+	Name     string `gorm:"uniqueIndex"`
+	Email    string
+	Status   int
+	Balance  uint64
+	Verified bool
+	Bio      string `gorm:"type:text"`
+}
+
+// benchOrder model with composite primary key and nullable columns
+type benchOrder struct {
+	TenantID uint64 `gorm:"primaryKey"`
+	OrderNo  string `gorm:"primaryKey"`
+	UserID   uint64
+	Amount   float64
+	State    string
+	PaidAt   *time.Time
+	Remark   string `gorm:"type:text"`
+	Created  time.Time
+}
+
+func newBenchUser(textSize int) *benchUser {
+	now := time.Now()
+	return &benchUser{
+		Model:   gorm.Model{ID: 10086, CreatedAt: now.Add(-time.Hour), UpdatedAt: now},
+		Name:    "zhyeah",
+		Email:   "zhyeah@example.com",
+		Status:  1,
+		Balance: 1<<63 + 7,
+		Bio:     strings.Repeat("gorm-cache ", textSize/11+1)[:textSize],
+	}
+}
+
+func newBenchOrder(textSize int) *benchOrder {
+	now := time.Now()
+	return &benchOrder{
+		TenantID: 42,
+		OrderNo:  "20220101-000123",
+		UserID:   10086,
+		Amount:   99.95,
+		State:    "paid",
+		PaidAt:   &now,
+		Remark:   strings.Repeat("gorm-cache ", textSize/11+1)[:textSize],
+		Created:  now.Add(-time.Minute),
+	}
+}
+
+func TestMsgpackSerializer(t *testing.T) {
+	checkRoundTrip(t, &MsgpackSerializer{}, newBenchUser(64))
+	checkRoundTrip(t, &MsgpackSerializer{}, newBenchOrder(64))
+	checkRoundTrip(t, &MsgpackSerializer{}, &[]benchOrder{*newBenchOrder(8), *newBenchOrder(16)})
+}
+
+func TestGobSerializer(t *testing.T) {
+	checkRoundTrip(t, &GobSerializer{}, newBenchUser(64))
+	checkRoundTrip(t, &GobSerializer{}, newBenchOrder(64))
+	checkRoundTrip(t, &GobSerializer{}, &[]benchOrder{*newBenchOrder(8), *newBenchOrder(16)})
+}
+
+func TestProtobufSerializer(t *testing.T) {
+	s := &ProtobufSerializer{}
+	ts := timestamppb.New(time.Unix(1600000000, 123456789))
+	data, err := s.Serialize(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := proto.Marshal(ts)
+	if string(data) != string(want) {
+		t.Fatal("proto message not serialized by protobuf")
+	}
+	decoded := &timestamppb.Timestamp{}
+	err = s.Deserialize(data, decoded)
+	if err != nil || !proto.Equal(ts, decoded) {
+		t.Fatalf("round trip of proto message: got %v %v, want %v", decoded, err, ts)
+	}
+
+	value := wrapperspb.String("gorm-cache")
+	data, err = s.Serialize(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedValue := &wrapperspb.StringValue{}
+	err = s.Deserialize(data, decodedValue)
+	if err != nil || decodedValue.GetValue() != "gorm-cache" {
+		t.Fatalf("round trip of proto message: got %v %v", decodedValue, err)
+	}
+}
+
+func TestProtobufSerializerFallback(t *testing.T) {
+	// values which are not proto.Message are serialized by json if no fallback
+	s := &ProtobufSerializer{}
+	data, err := s.Serialize(map[string]int64{"count": 3})
+	if err != nil || string(data) != `{"count":3}` {
+		t.Fatalf("fallback to json: got %s %v", data, err)
+	}
+	checkRoundTrip(t, s, newBenchOrder(16))
+
+	s = &ProtobufSerializer{Fallback: &GobSerializer{}}
+	checkRoundTrip(t, s, newBenchUser(16))
+	var count int64
+	data, err = s.Serialize(int64(7))
+	if err == nil {
+		err = s.Deserialize(data, &count)
+	}
+	if err != nil || count != 7 {
+		t.Fatalf("fallback to gob: got %d %v", count, err)
+	}
+}
+
+// checkRoundTrip check that objPtr is the same after serialized and deserialized, times are compared by instant
+func checkRoundTrip(t *testing.T, s Serializer, objPtr interface{}) {
+	t.Helper()
+	data, err := s.Serialize(objPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := reflect.New(reflect.TypeOf(objPtr).Elem())
+	err = s.Deserialize(data, decoded.Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalValues(reflect.ValueOf(objPtr).Elem(), decoded.Elem()) {
+		t.Fatalf("round trip of %T by %T: got %+v, want %+v", objPtr, s, decoded.Elem().Interface(), reflect.ValueOf(objPtr).Elem().Interface())
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// equalValues deep equal, but times are compared by instant
+func equalValues(a, b reflect.Value) bool {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).IsExported() && !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// serializers compared by benchmarks, the compressed ones compress values larger than 1024 bytes (default threshold)
+var benchSerializers = []struct {
+	name       string
+	serializer Serializer
+}{
+	{"json", &JSONSerializer{}},
+	{"msgpack", &MsgpackSerializer{}},
+	{"gob", &GobSerializer{}},
+	{"json+snappy", &CompressSerializer{Algorithm: constant.CompressionSnappy}},
+	{"json+zstd", &CompressSerializer{Algorithm: constant.CompressionZstd}},
+	{"json+gzip", &CompressSerializer{Algorithm: constant.CompressionGzip}},
+	{"msgpack+snappy", &CompressSerializer{Serializer: &MsgpackSerializer{}}},
+}
+
+// benchModels models of benchmarks, with small and large TEXT columns
+var benchModels = []struct {
+	name string
+	obj  interface{}
+}{
+	{"User/text=64", newBenchUser(64)},
+	{"User/text=4096", newBenchUser(4096)},
+	{"Order/text=64", newBenchOrder(64)},
+	{"Order/text=4096", newBenchOrder(4096)},
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	for _, m := range benchModels {
+		for _, s := range benchSerializers {
+			b.Run(m.name+"/"+s.name, func(b *testing.B) {
+				data, err := s.serializer.Serialize(m.obj)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err = s.serializer.Serialize(m.obj)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data)), "bytes")
+			})
+		}
+	}
+}
+
+func BenchmarkDeserialize(b *testing.B) {
+	for _, m := range benchModels {
+		for _, s := range benchSerializers {
+			b.Run(m.name+"/"+s.name, func(b *testing.B) {
+				data, err := s.serializer.Serialize(m.obj)
+				if err != nil {
+					b.Fatal(err)
+				}
+				objType := reflect.TypeOf(m.obj).Elem()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					err = s.serializer.Deserialize(data, reflect.New(objType).Interface())
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data)), "bytes")
+			})
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrCacheMiss means that a Get failed because the item wasn't present.
+	ErrCacheMiss = errors.New("gormcache: cache miss")
+	// ErrNotStored means that a conditional write (Add) failed because the condition was not satisfied.
+	ErrNotStored = errors.New("gormcache: item not stored")
+	// ErrCASConflict means that a CAS failed because the item was modified after it was read.
+	ErrCASConflict = errors.New("gormcache: compare-and-swap conflict")
+)
+
+// Item cache item read from or written to a CacheStore
+type Item struct {
+	Key        string
+	Value      []byte
+	Expiration int32 // expire time in seconds, 0 means never expire
+
+	casToken interface{} // backend specific token filled by Get/GetMulti, consumed by CAS
+}
+
+// CacheStore cache backend used by CacheDaoBase, implementations should return ctx.Err() once ctx is done
+type CacheStore interface {
+	// Get get item by key, return ErrCacheMiss if absent
+	Get(ctx context.Context, key string) (*Item, error)
+	// GetMulti get items by keys, absent keys are not contained in the result map
+	GetMulti(ctx context.Context, keys []string) (map[string]*Item, error)
+	// Set set item unconditionally
+	Set(ctx context.Context, item *Item) error
+	// Add set item only if the key is absent, return ErrNotStored otherwise
+	Add(ctx context.Context, item *Item) error
+	// Delete delete item by key, return ErrCacheMiss if absent
+	Delete(ctx context.Context, key string) error
+	// CAS set item only if it wasn't modified since it was got, return ErrCASConflict otherwise
+	CAS(ctx context.Context, item *Item) error
+	// Incr increase the numeric value of key by delta, return ErrCacheMiss if absent
+	Incr(ctx context.Context, key string, delta uint64) (uint64, error)
+}
+
+// DefaultStore global cache store, used by CacheDaoBase which has no 'Store' specified
+var DefaultStore CacheStore
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName instrumentation name of the spans
+const tracerName = "github.com/zhyeah/gorm-cache"
+
+// span attribute keys
+const (
+	attrDao             = attribute.Key("gormcache.dao")
+	attrMethod          = attribute.Key("gormcache.method")
+	attrKeyCount        = attribute.Key("gormcache.key_count")
+	attrHitCount        = attribute.Key("gormcache.hit_count")
+	attrAbsentIds       = attribute.Key("gormcache.absent_ids")
+	attrAbsentCount     = attribute.Key("gormcache.absent_count")
+	attrId              = attribute.Key("gormcache.id")
+	attrVersionKeyCount = attribute.Key("gormcache.version_key_count")
+	attrOffset          = attribute.Key("gormcache.offset")
+	attrLimit           = attribute.Key("gormcache.limit")
+)
+
+// max primary keys recorded in absent ids attribute, large batches are recorded by absent count only
+const maxTracedAbsentIds = 32
+
+// DefaultTracerProvider tracer provider used by cache daos which have no 'TracerProvider' set, no spans if nil.
+// set it to 'otel.GetTracerProvider()' to use the global one.
+var DefaultTracerProvider trace.TracerProvider
+
+// tracer get the tracer of this dao, nil if tracing is disabled
+func (base *CacheDaoBase) tracer() trace.Tracer {
+	provider := base.TracerProvider
+	if provider == nil {
+		provider = DefaultTracerProvider
+	}
+	if provider == nil {
+		return nil
+	}
+	return provider.Tracer(tracerName)
+}
+
+// startSpan start span of a phase, named 'gormcache.{name}'. ctx is returned as it is with nil span if tracing is disabled,
+// all methods of nil span are no-op.
+func (base *CacheDaoBase) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *phaseSpan) {
+	tracer := base.tracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	attrs = append(attrs, attrDao.String(base.ObjectCachePrefix))
+	spanCtx, span := tracer.Start(ctx, "gormcache."+name, trace.WithAttributes(attrs...))
+	return keepBackground(ctx, spanCtx), &phaseSpan{span: span, base: base}
+}
+
+// phaseSpan span of a phase of cache read or write
+type phaseSpan struct {
+	span     trace.Span
+	base     *CacheDaoBase
+	counting bool // hit count is recorded for the span with key count
+	hits     int
+}
+
+// keys record the count of keys
+func (s *phaseSpan) keys(n int) {
+	if s != nil {
+		s.counting = true
+		s.span.SetAttributes(attrKeyCount.Int(n))
+	}
+}
+
+// hit accumulate the count of hits, recorded when the span ends
+func (s *phaseSpan) hit(n int) {
+	if s != nil {
+		s.hits += n
+	}
+}
+
+// absent record the count of primary keys absent in cache, and the first 'maxTracedAbsentIds' of them
+func (s *phaseSpan) absent(keys []interface{}) {
+	if s == nil || !s.span.IsRecording() {
+		return
+	}
+	n := len(keys)
+	if n > maxTracedAbsentIds {
+		n = maxTracedAbsentIds
+	}
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, s.base.encodeKey(keys[i]))
+	}
+	s.span.SetAttributes(attrAbsentIds.StringSlice(ids), attrAbsentCount.Int(len(keys)))
+}
+
+// id record the primary key
+func (s *phaseSpan) id(key interface{}) {
+	if s != nil && s.span.IsRecording() {
+		s.span.SetAttributes(attrId.String(s.base.encodeKey(key)))
+	}
+}
+
+// fail record err on span, nil err and cache miss are ignored
+func (s *phaseSpan) fail(err error) {
+	if s != nil && err != nil && !errors.Is(err, ErrCacheMiss) {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// end end the span, use it with defer, the error pointed by errp (if not nil) is recorded
+func (s *phaseSpan) end(errp *error) {
+	if s == nil {
+		return
+	}
+	if errp != nil {
+		s.fail(*errp)
+	}
+	if s.counting {
+		s.span.SetAttributes(attrHitCount.Int(s.hits))
+	}
+	s.span.End()
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func newTracedTestCacheDao(t *testing.T) (*testUserCacheDao, *gorm.DB, *tracetest.SpanRecorder) {
+	db := newTestDB(t)
+	recorder := tracetest.NewSpanRecorder()
+	dao := newTestCacheDao(t, db, func(dao *testUserCacheDao) {
+		dao.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	})
+	return dao, db, recorder
+}
+
+func TestTracingGetByIds(t *testing.T) {
+	dao, db, recorder := newTracedTestCacheDao(t)
+	u1 := &testUser{Name: "alice", Status: 1}
+	u2 := &testUser{Name: "bob", Status: 1}
+	db.Create(u1)
+	db.Create(u2)
+	ids := []uint64{u1.Id, u2.Id, 999}
+
+	ctx, root := dao.TracerProvider.Tracer("test").Start(context.Background(), "root")
+	_, err := dao.GetByIdsContext(ctx, ids)
+	root.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := lastSpan(t, recorder, "gormcache.GetByIds")
+	if span.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Fatal("span of GetByIds is not child of the span in ctx")
+	}
+	checkSpanAttrs(t, span, map[attribute.Key]string{
+		attrDao:         "testUser",
+		attrKeyCount:    "3",
+		attrHitCount:    "0",
+		attrAbsentCount: "3",
+		attrAbsentIds:   fmt.Sprintf("[%d %d 999]", u1.Id, u2.Id),
+	})
+	for _, name := range []string{"gormcache.GetObjectVersions", "gormcache.GetObjectCaches", "gormcache.LoadObjects"} {
+		if lastSpan(t, recorder, name).Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Fatalf("span %s is not child of GetByIds", name)
+		}
+	}
+
+	// object caches are set in background
+	waitForSpans(t, recorder, "gormcache.SetObjectCache", 2)
+	_, err = dao.GetByIds(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSpanAttrs(t, lastSpan(t, recorder, "gormcache.GetByIds"), map[attribute.Key]string{
+		attrKeyCount:    "3",
+		attrHitCount:    "2",
+		attrAbsentCount: "1",
+		attrAbsentIds:   "[999]",
+	})
+}
+
+func TestTracingGetByConcreteKey(t *testing.T) {
+	dao, db, recorder := newTracedTestCacheDao(t)
+	db.Create(&testUser{Name: "alice", Status: 1})
+
+	for i, hits := range []string{"0", "1"} {
+		user, err := dao.GetByName("alice")
+		if err != nil || user == nil {
+			t.Fatalf("GetByName: got %v %v", user, err)
+		}
+		checkSpanAttrs(t, lastSpan(t, recorder, "gormcache.GetByConcreteKey"), map[attribute.Key]string{
+			attrMethod:   "GetByName",
+			attrKeyCount: "1",
+			attrHitCount: hits,
+		})
+		if i == 0 {
+			sqlSpan := lastSpan(t, recorder, "gormcache.SQLDao")
+			checkSpanAttrs(t, sqlSpan, map[attribute.Key]string{attrMethod: "GetByName"})
+		}
+	}
+	if n := len(spansNamed(recorder, "gormcache.SQLDao")); n != 1 {
+		t.Fatalf("spans of sql dao: got %d, want 1", n)
+	}
+}
+
+func TestTracingAbsentIdsCapped(t *testing.T) {
+	dao, _, recorder := newTracedTestCacheDao(t)
+	ids := make([]uint64, 0)
+	for i := 0; i < maxTracedAbsentIds+8; i++ {
+		ids = append(ids, uint64(1000+i))
+	}
+	_, err := dao.GetByIds(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := lastSpan(t, recorder, "gormcache.GetByIds")
+	checkSpanAttrs(t, span, map[attribute.Key]string{attrAbsentCount: fmt.Sprint(len(ids))})
+	if n := len(spanAttr(span, attrAbsentIds).AsStringSlice()); n != maxTracedAbsentIds {
+		t.Fatalf("absent ids recorded: got %d, want %d", n, maxTracedAbsentIds)
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	dao, db, recorder := newTracedTestCacheDao(t)
+	err := db.Migrator().DropTable(&testUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dao.GetById(1)
+	if err == nil {
+		t.Fatal("GetById without table should fail")
+	}
+
+	for _, name := range []string{"gormcache.GetById", "gormcache.LoadObject"} {
+		span := lastSpan(t, recorder, name)
+		if span.Status().Code != codes.Error || !strings.Contains(span.Status().Description, "no such table") {
+			t.Fatalf("status of %s: got %v, want error", name, span.Status())
+		}
+	}
+	// cache miss is not an error
+	span := lastSpan(t, recorder, "gormcache.GetObjectVersion")
+	if span.Status().Code != codes.Unset {
+		t.Fatalf("status of cache miss: got %v, want unset", span.Status())
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	dao := newTestCacheDao(t, newTestDB(t), nil)
+	ctx := context.Background()
+	spanCtx, span := dao.startSpan(ctx, "GetById")
+	if span != nil || spanCtx != ctx {
+		t.Fatal("span started without tracer provider")
+	}
+	// methods of nil span are no-op
+	span.keys(1)
+	span.hit(1)
+	span.absent([]interface{}{1})
+	span.fail(gorm.ErrInvalidData)
+	span.end(nil)
+}
+
+func spansNamed(recorder *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	spans := make([]sdktrace.ReadOnlySpan, 0)
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func lastSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := spansNamed(recorder, name)
+	if len(spans) == 0 {
+		t.Fatalf("no span %s", name)
+	}
+	return spans[len(spans)-1]
+}
+
+func waitForSpans(t *testing.T, recorder *tracetest.SpanRecorder, name string, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if len(spansNamed(recorder, name)) >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("less than %d spans %s", n, name)
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// checkSpanAttrs check the attributes of span by their emitted strings
+func checkSpanAttrs(t *testing.T, span sdktrace.ReadOnlySpan, want map[attribute.Key]string) {
+	t.Helper()
+	for key, value := range want {
+		if got := spanAttr(span, key).Emit(); got != value {
+			t.Fatalf("attribute %s of %s: got %q, want %q", key, span.Name(), got, value)
+		}
+	}
+}
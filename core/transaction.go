@@ -0,0 +1,321 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"gorm.io/gorm"
+)
+
+// pendingInvalidation invalidation queued in transaction
+type pendingInvalidation struct {
+	base        *CacheDaoBase
+	key         interface{}
+	versionKeys []string
+}
+
+// invalidationQueue invalidations of a transaction, flushed after commit and dropped after rollback
+type invalidationQueue struct {
+	lock       sync.Mutex
+	items      []*pendingInvalidation
+	savepoints map[string]int // savepoint name -> count of items before it
+	done       bool           // committed or rolled back
+}
+
+// push queue the item, false if the transaction is finished
+func (q *invalidationQueue) push(item *pendingInvalidation) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.done {
+		return false
+	}
+	q.items = append(q.items, item)
+	return true
+}
+
+// mark count of items queued, for 'truncate' when the part of transaction after it is rolled back
+func (q *invalidationQueue) mark() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
+func (q *invalidationQueue) truncate(mark int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if mark < len(q.items) {
+		q.items = q.items[:mark]
+	}
+}
+
+func (q *invalidationQueue) savepoint(name string) {
+	mark := q.mark()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.savepoints == nil {
+		q.savepoints = make(map[string]int)
+	}
+	q.savepoints[name] = mark
+}
+
+func (q *invalidationQueue) rollbackTo(name string) {
+	q.lock.Lock()
+	mark, ok := q.savepoints[name]
+	q.lock.Unlock()
+	if ok {
+		q.truncate(mark)
+	}
+}
+
+func (q *invalidationQueue) flush() {
+	q.lock.Lock()
+	items := q.items
+	q.items = nil
+	q.done = true
+	q.lock.Unlock()
+
+	for _, item := range items {
+		item.base.invalidate(context.Background(), item.key, item.versionKeys)
+		item.base.scheduleDelayedInvalidation(item.key, item.versionKeys)
+	}
+}
+
+func (q *invalidationQueue) drop() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.items = nil
+	q.done = true
+}
+
+// txConnPool 'gorm.ConnPool' of the db using 'InvalidationPlugin', the transactions began from it
+// (by 'gorm.DB.Transaction', 'gorm.DB.Begin' or the default transaction of gorm) are hooked,
+// so the invalidations issued in them are deferred until commit.
+type txConnPool struct {
+	gorm.ConnPool
+}
+
+// BeginTx begin transaction by the wrapped conn pool and hook it
+func (p *txConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		connPool, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = connPool
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	return &hookedTx{ConnPool: tx, queue: &invalidationQueue{}}, nil
+}
+
+// GetDBConn get *sql.DB for 'gorm.DB.DB'
+func (p *txConnPool) GetDBConn() (*sql.DB, error) {
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok && connector != nil {
+		return connector.GetDBConn()
+	}
+	if db, ok := p.ConnPool.(*sql.DB); ok {
+		return db, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// hookedTx transaction began from txConnPool, flush the invalidations after commit and drop them on rollback.
+// the savepoints (of nested 'gorm.DB.Transaction') are seen from the sql executed,
+// the invalidations after the savepoint rolled back to are dropped.
+type hookedTx struct {
+	gorm.ConnPool
+	queue *invalidationQueue
+}
+
+// Commit commit the transaction, then flush the invalidations
+func (tx *hookedTx) Commit() error {
+	committer, ok := tx.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	err := committer.Commit()
+	if err != nil {
+		tx.queue.drop()
+		return err
+	}
+	tx.queue.flush()
+	return nil
+}
+
+// Rollback rollback the transaction, and drop the invalidations
+func (tx *hookedTx) Rollback() error {
+	tx.queue.drop()
+	committer, ok := tx.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	return committer.Rollback()
+}
+
+// ExecContext exec by the transaction, and track the savepoints
+func (tx *hookedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ret, err := tx.ConnPool.ExecContext(ctx, query, args...)
+	if err == nil {
+		tx.trackSavepoint(query)
+	}
+	return ret, err
+}
+
+// prefixes of the savepoint statements of dialectors, sql server uses 'SAVE TRANSACTION'
+var (
+	savepointPrefixes  = []string{"SAVEPOINT ", "SAVE TRANSACTION "}
+	rollbackToPrefixes = []string{"ROLLBACK TO SAVEPOINT ", "ROLLBACK TRANSACTION "}
+)
+
+func (tx *hookedTx) trackSavepoint(query string) {
+	query = strings.TrimSpace(query)
+	upper := strings.ToUpper(query)
+	for _, prefix := range savepointPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			tx.queue.savepoint(strings.TrimSpace(query[len(prefix):]))
+			return
+		}
+	}
+	for _, prefix := range rollbackToPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			tx.queue.rollbackTo(strings.TrimSpace(query[len(prefix):]))
+			return
+		}
+	}
+}
+
+// hookTransactions hook the transactions began from db, invoked by 'InvalidationPlugin'
+func hookTransactions(db *gorm.DB) {
+	if _, ok := db.ConnPool.(*txConnPool); ok {
+		return
+	}
+	db.ConnPool = &txConnPool{ConnPool: db.ConnPool}
+	db.Statement.ConnPool = db.ConnPool
+}
+
+// invalidation queues of the transactions began by 'Transaction' or 'Begin', keyed by 'gorm.ConnPool' of transaction
+var txQueues sync.Map
+
+func loadTxQueue(db *gorm.DB) *invalidationQueue {
+	if db == nil || db.Statement == nil || db.Statement.ConnPool == nil {
+		return nil
+	}
+	if tx, ok := db.Statement.ConnPool.(*hookedTx); ok {
+		return tx.queue
+	}
+	if queue, ok := txQueues.Load(db.Statement.ConnPool); ok {
+		return queue.(*invalidationQueue)
+	}
+	return nil
+}
+
+// Transaction like 'gorm.DB.Transaction', but the invalidations issued by 'NotifyModifiedTx' (and the plugin) in it
+// are deferred until commit, and dropped on rollback.
+// it's not needed for the db using 'InvalidationPlugin', whose transactions are all deferred.
+func Transaction(db *gorm.DB, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	// nested transaction shares the queue of the outermost one, drops its part when it's rolled back to savepoint
+	if queue := loadTxQueue(db); queue != nil {
+		mark := queue.mark()
+		err := db.Transaction(fc, opts...)
+		if err != nil && !db.DisableNestedTransaction {
+			queue.truncate(mark)
+		}
+		return err
+	}
+
+	queue := &invalidationQueue{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		txQueues.Store(tx.Statement.ConnPool, queue)
+		defer txQueues.Delete(tx.Statement.ConnPool)
+		return fc(tx)
+	}, opts...)
+	if err == nil {
+		queue.flush()
+	}
+	return err
+}
+
+// Begin like 'gorm.DB.Begin', finish it with 'Commit' or 'Rollback' to flush or drop the deferred invalidations
+func Begin(db *gorm.DB, opts ...*sql.TxOptions) *gorm.DB {
+	tx := db.Begin(opts...)
+	if tx.Error == nil {
+		txQueues.Store(tx.Statement.ConnPool, &invalidationQueue{})
+	}
+	return tx
+}
+
+// Commit commit transaction began by 'Begin', then flush the deferred invalidations
+func Commit(tx *gorm.DB) *gorm.DB {
+	queue := loadTxQueue(tx)
+	connPool := tx.Statement.ConnPool
+	ret := tx.Commit()
+	txQueues.Delete(connPool)
+	if ret.Error == nil && queue != nil {
+		queue.flush()
+	}
+	return ret
+}
+
+// Rollback rollback transaction began by 'Begin', and drop the deferred invalidations
+func Rollback(tx *gorm.DB) *gorm.DB {
+	txQueues.Delete(tx.Statement.ConnPool)
+	return tx.Rollback()
+}
+
+// NotifyModifiedTx like 'NotifyModified', but if db is a transaction began by 'Transaction' or 'Begin',
+// or any transaction of the db using 'InvalidationPlugin', the invalidation is deferred until it's committed.
+// other transactions began by gorm directly can't be deferred, they're invalidated right away as 'NotifyModified' does.
+func (base *CacheDaoBase) NotifyModifiedTx(db *gorm.DB, curDo interface{}) error {
+	if curDo == nil {
+		return nil
+	}
+
+	key, versionKeys := base.makeInvalidation(curDo)
+	return base.invalidateTx(db, key, versionKeys)
+}
+
+// NotifyUpdatedTx like 'NotifyUpdated', but the invalidation is deferred until commit as 'NotifyModifiedTx' does.
+func (base *CacheDaoBase) NotifyUpdatedTx(db *gorm.DB, before, after interface{}) error {
+	if after == nil {
+		return base.NotifyModifiedTx(db, before)
+	}
+	if before == nil {
+		before = base.getCachedObject(context.Background(), base.GetPrimaryKey(after))
+	}
+
+	key, versionKeys := base.makeUpdateInvalidation(before, after)
+	return base.invalidateTx(db, key, versionKeys)
+}
+
+// invalidateTx invalidate right now, or defer it if db is a transaction not finished yet.
+// the error of invalidating right now is returned, the errors after commit are logged only.
+func (base *CacheDaoBase) invalidateTx(db *gorm.DB, key interface{}, versionKeys []string) error {
+	queue := loadTxQueue(db)
+	if queue != nil && queue.push(&pendingInvalidation{base: base, key: key, versionKeys: versionKeys}) {
+		return nil
+	}
+
+	ctx := context.Background()
+	if db != nil && db.Statement != nil && db.Statement.Context != nil {
+		ctx = db.Statement.Context
+	}
+	if queue == nil && db != nil && db.Statement != nil {
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			base.logger().Warn("invalidate before commit, use 'InvalidationPlugin' or begin transaction by 'core.Transaction' to defer it", log.Key(key))
+		}
+	}
+	err := base.invalidate(ctx, key, versionKeys)
+	base.scheduleDelayedInvalidation(key, versionKeys)
+	return err
+}
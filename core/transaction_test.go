@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestTransactionDefersInvalidation(t *testing.T) {
+	db := newTestDB(t)
+	dao := newTestCacheDao(t, db, nil)
+	user := &testUser{Name: "alice", Status: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// committed: invalidated after commit
+	cacheTestObject(t, dao, user.Id)
+	err := Transaction(db, func(tx *gorm.DB) error {
+		if err := dao.NotifyModifiedTx(tx, user); err != nil {
+			return err
+		}
+		if !testObjectCached(t, dao, user.Id) {
+			t.Fatal("invalidated before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testObjectCached(t, dao, user.Id) {
+		t.Fatal("not invalidated after commit")
+	}
+
+	// rolled back: dropped
+	cacheTestObject(t, dao, user.Id)
+	err = Transaction(db, func(tx *gorm.DB) error {
+		dao.NotifyModifiedTx(tx, user)
+		return errors.New("rollback")
+	})
+	if err == nil || !testObjectCached(t, dao, user.Id) {
+		t.Fatalf("invalidated after rollback: %v", err)
+	}
+
+	// began by Begin
+	cacheTestObject(t, dao, user.Id)
+	tx := Begin(db)
+	dao.NotifyModifiedTx(tx, user)
+	if !testObjectCached(t, dao, user.Id) {
+		t.Fatal("invalidated before commit")
+	}
+	if err := Commit(tx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if testObjectCached(t, dao, user.Id) {
+		t.Fatal("not invalidated after commit")
+	}
+
+	// transaction of gorm can't be deferred without plugin
+	cacheTestObject(t, dao, user.Id)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		dao.NotifyModifiedTx(tx, user)
+		if testObjectCached(t, dao, user.Id) {
+			t.Fatal("not invalidated in transaction of gorm")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransactionNestedRollback(t *testing.T) {
+	db := newTestDB(t)
+	dao := newTestCacheDao(t, db, nil)
+	alice, bob := createTestUsers(t, db)
+
+	cacheTestObject(t, dao, alice.Id)
+	cacheTestObject(t, dao, bob.Id)
+	err := Transaction(db, func(tx *gorm.DB) error {
+		dao.NotifyModifiedTx(tx, alice)
+		// rolled back to savepoint, the invalidation of bob is dropped
+		Transaction(tx, func(tx *gorm.DB) error {
+			dao.NotifyModifiedTx(tx, bob)
+			return errors.New("rollback")
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testObjectCached(t, dao, alice.Id) || !testObjectCached(t, dao, bob.Id) {
+		t.Fatal("invalidations of nested transaction rolled back are not dropped")
+	}
+}
+
+func TestPluginDefersNativeTransaction(t *testing.T) {
+	for _, prepareStmt := range []bool{false, true} {
+		db := newTestDBWithConfig(t, &gorm.Config{PrepareStmt: prepareStmt})
+		if err := db.Use(NewInvalidationPlugin()); err != nil {
+			t.Fatal(err)
+		}
+		dao := newTestCacheDao(t, db, nil)
+		alice, bob := createTestUsers(t, db)
+
+		// committed: invalidated after commit
+		cacheTestObject(t, dao, alice.Id)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(alice).Update("status", 2).Error; err != nil {
+				return err
+			}
+			if !testObjectCached(t, dao, alice.Id) {
+				t.Fatal("invalidated before commit")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testObjectCached(t, dao, alice.Id) {
+			t.Fatal("not invalidated after commit")
+		}
+
+		// rolled back: dropped
+		cacheTestObject(t, dao, alice.Id)
+		tx := db.Begin()
+		tx.Model(alice).Update("status", 3)
+		if err := tx.Rollback().Error; err != nil {
+			t.Fatal(err)
+		}
+		if !testObjectCached(t, dao, alice.Id) {
+			t.Fatal("invalidated after rollback")
+		}
+
+		// nested transaction rolled back to savepoint
+		cacheTestObject(t, dao, bob.Id)
+		tx = db.Begin()
+		tx.Model(alice).Update("status", 4)
+		tx.Transaction(func(tx *gorm.DB) error {
+			tx.Model(bob).Update("status", 4)
+			return errors.New("rollback")
+		})
+		if !testObjectCached(t, dao, alice.Id) {
+			t.Fatal("invalidated before commit")
+		}
+		if err := tx.Commit().Error; err != nil {
+			t.Fatal(err)
+		}
+		if testObjectCached(t, dao, alice.Id) || !testObjectCached(t, dao, bob.Id) {
+			t.Fatalf("invalidations after commit with savepoint rolled back (prepare stmt %v)", prepareStmt)
+		}
+
+		// finished transaction invalidates right away
+		dao.NotifyModifiedTx(tx, bob)
+		if testObjectCached(t, dao, bob.Id) {
+			t.Fatal("not invalidated after transaction finished")
+		}
+	}
+}
+
+func createTestUsers(t *testing.T, db *gorm.DB) (*testUser, *testUser) {
+	t.Helper()
+	alice := &testUser{Name: "alice", Status: 1}
+	bob := &testUser{Name: "bob", Status: 1}
+	for _, user := range []*testUser{alice, bob} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return alice, bob
+}
+
+// cacheTestObject load object into cache store
+func cacheTestObject(t *testing.T, dao *testUserCacheDao, id uint64) {
+	t.Helper()
+	if _, err := dao.GetById(id); err != nil {
+		t.Fatal(err)
+	}
+	if !testObjectCached(t, dao, id) {
+		t.Fatal("object not cached")
+	}
+}
+
+func testObjectCached(t *testing.T, dao *testUserCacheDao, id uint64) bool {
+	t.Helper()
+	ctx := context.Background()
+	key, err := dao.getObjectKey(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == "" {
+		return false
+	}
+	_, err = dao.cacheStore().Get(ctx, key)
+	return err == nil
+}
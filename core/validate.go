@@ -0,0 +1,141 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/zhyeah/gorm-cache/constant"
+	"github.com/zhyeah/gorm-cache/util"
+	"gorm.io/gorm"
+)
+
+var (
+	gormDBType       = reflect.TypeOf(&gorm.DB{})
+	knownNotifyTypes = map[string]bool{
+		constant.NotifyTypeConcrete:  true,
+		constant.NotifyTypeList:      true,
+		constant.NotifyTypeRange:     true,
+		constant.NotifyTypeAggregate: true,
+		constant.NotifyTypeMulti:     true,
+	}
+)
+
+// validateNotifyInfos validate all the registered notify infos, the problems of all methods are returned together
+func (base *CacheDaoBase) validateNotifyInfos() error {
+	methodNames := make([]string, 0, len(base.MethodNotifyInfoMap))
+	for methodName := range base.MethodNotifyInfoMap {
+		methodNames = append(methodNames, methodName)
+	}
+	sort.Strings(methodNames)
+
+	problems := make([]string, 0)
+	for _, methodName := range methodNames {
+		info := base.MethodNotifyInfoMap[methodName]
+		problems = append(problems, base.checkNotify(methodName, info.Type, info.Fields, info.Args)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid notify info of %s:\n%s", base.ObjectCachePrefix, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// checkNotify check notify declaration against the sql dao method and Do fields, return the problems found
+func (base *CacheDaoBase) checkNotify(methodName string, notifyType string, keys []string, args []int) []string {
+	problems := make([]string, 0)
+	addProblem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf("method '%s': ", methodName)+fmt.Sprintf(format, a...))
+	}
+
+	if !knownNotifyTypes[notifyType] {
+		addProblem("unknown notify type '%s'", notifyType)
+	}
+	if len(keys) != len(args) {
+		addProblem("%d keys but %d args", len(keys), len(args))
+	}
+	err := base.checkSQLMethod(methodName)
+	if err != nil {
+		addProblem("%v", err)
+		return problems
+	}
+
+	methodType := reflect.ValueOf(base.SQLDao).MethodByName(methodName).Type()
+	if notifyType == constant.NotifyTypeRange && (methodType.NumIn() == 0 || methodType.In(0) != gormDBType) {
+		addProblem("the first parameter of range method should be '*gorm.DB'")
+	}
+
+	var doType reflect.Type
+	if base.Do != nil {
+		doType = util.GetPointToType(reflect.TypeOf(base.Do))
+	}
+	for i, key := range keys {
+		var field reflect.StructField
+		ok := false
+		if doType != nil {
+			field, ok = doType.FieldByName(key)
+			if !ok {
+				addProblem("key '%s' is not a field of '%s'", key, doType.Name())
+			}
+		}
+		if i >= len(args) {
+			continue
+		}
+		if args[i] < 0 || args[i] >= methodType.NumIn() {
+			addProblem("arg index %d of key '%s' out of range, method has %d parameters", args[i], key, methodType.NumIn())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		argType := methodType.In(args[i])
+		if notifyType == constant.NotifyTypeList {
+			argType = util.GetPointToType(argType)
+			if argType.Kind() != reflect.Slice && argType.Kind() != reflect.Array {
+				addProblem("arg %d of key '%s' should be slice for list method, got %v", args[i], key, methodType.In(args[i]))
+				continue
+			}
+			argType = argType.Elem()
+		}
+		if !compatibleKeyType(argType, field.Type) {
+			addProblem("arg %d of type %v is not compatible with field '%s' of type %v", args[i], argType, key, field.Type)
+		}
+	}
+	return problems
+}
+
+// compatibleKeyType check if the arg value makes the same version key as the field value,
+// the numbers of any size are compatible as they are formatted to the same string.
+func compatibleKeyType(argType, fieldType reflect.Type) bool {
+	argType = util.GetPointToType(argType)
+	fieldType = util.GetPointToType(fieldType)
+	if argType == fieldType || argType.Kind() == reflect.Interface {
+		return true
+	}
+	return keyKindClass(argType.Kind()) != "" && keyKindClass(argType.Kind()) == keyKindClass(fieldType.Kind())
+}
+
+func keyKindClass(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// checkNotifyError like 'checkNotify', problems are returned as error
+func (base *CacheDaoBase) checkNotifyError(methodName string, notifyType string, keys []string, args []int) error {
+	problems := base.checkNotify(methodName, notifyType, keys, args)
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
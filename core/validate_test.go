@@ -0,0 +1,102 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+type badTypeCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concret;keys=['Name'];args=[0]"`
+}
+
+type badFuncCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByNmae;type=concrete;keys=['Name'];args=[0]"`
+}
+
+type badKeyCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concrete;keys=['Nmae'];args=[0]"`
+}
+
+type badArgCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concrete;keys=['Name'];args=[1]"`
+}
+
+type badArgsCountCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concrete;keys=['Name','Status'];args=[0]"`
+}
+
+type badArgTypeCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concrete;keys=['Status'];args=[0]"`
+}
+
+type badRangeCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=range;keys=['Name'];args=[0]"`
+}
+
+type badTagCacheDao struct {
+	CacheDaoBase
+	metaGetByName bool `notify:"func=GetByName;type=concrete;keys=[Name];args=[0]"`
+}
+
+type badMethodsCacheDao struct {
+	CacheDaoBase
+	metaGetByStatus bool `notify:"func=GetByStatus;type=range;keys=['Status'];args=[0]"`
+	metaGetByName   bool `notify:"func=GetByName;type=concret;keys=['Name'];args=[0]"`
+}
+
+// initTestCacheDao initialize cache dao of testUser, base should be embedded in dao
+func initTestCacheDao(t *testing.T, dao interface{ Initialize(interface{}) error }, base *CacheDaoBase) error {
+	base.Do = &testUser{}
+	base.SQLDao = &testUserSQLDao{db: newTestDB(t)}
+	base.Store = newMemStore()
+	t.Cleanup(func() { base.Close() })
+	return dao.Initialize(dao)
+}
+
+func TestInvalidNotifyTags(t *testing.T) {
+	cases := []struct {
+		name string
+		init func() error
+		want []string
+	}{
+		{"unknown type", func() error { dao := &badTypeCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"method 'GetByName': unknown notify type 'concret'"}},
+		{"unknown method", func() error { dao := &badFuncCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"method 'GetByNmae': sql dao has no method 'GetByNmae'"}},
+		{"unknown field", func() error { dao := &badKeyCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"key 'Nmae' is not a field of 'testUser'"}},
+		{"arg out of range", func() error { dao := &badArgCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"arg index 1 of key 'Name' out of range, method has 1 parameters"}},
+		{"keys and args mismatch", func() error { dao := &badArgsCountCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"2 keys but 1 args"}},
+		{"incompatible arg", func() error { dao := &badArgTypeCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"arg 0 of type string is not compatible with field 'Status' of type int"}},
+		{"range without db", func() error { dao := &badRangeCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"the first parameter of range method should be '*gorm.DB'"}},
+		{"malformed tag", func() error { dao := &badTagCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"invalid character"}},
+		{"problems of all methods", func() error { dao := &badMethodsCacheDao{}; return initTestCacheDao(t, dao, &dao.CacheDaoBase) },
+			[]string{"method 'GetByName': unknown notify type", "method 'GetByStatus': arg 0 of type *gorm.DB is not compatible"}},
+	}
+	for _, c := range cases {
+		err := c.init()
+		if err == nil {
+			t.Fatalf("%s: initialize should fail", c.name)
+		}
+		last := -1
+		for _, want := range c.want {
+			i := strings.Index(err.Error(), want)
+			if i <= last {
+				t.Fatalf("%s: %q not found in order in error: %v", c.name, want, err)
+			}
+			last = i
+		}
+	}
+}
@@ -1,11 +1,96 @@
 package log
 
-import "github.com/sirupsen/logrus"
+import (
+	stdlog "log"
+	"os"
+	"time"
+)
 
-var Logger = logrus.New()
+// Default logger used by cache daos which have no 'Logger' set, it logs errors to stderr,
+// use 'NewStdLogger' for another level, or the adapters in 'log/zap', 'log/logrus' and 'NewSlogLogger' to route logs elsewhere.
+var Default Interface = NewStdLogger(stdlog.New(os.Stderr, "", stdlog.LstdFlags), ErrorLevel)
 
-func init() {
-	// Logger.SetLevel(logrus.DebugLevel)
-	Logger.SetLevel(logrus.ErrorLevel)
-	Logger.SetFormatter(&logrus.TextFormatter{})
+// Interface logger of cache dao, implement it or use the adapters to route logs into your pipeline
+type Interface interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Field structured log field
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F make field of key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Dao field of cache dao name
+func Dao(name string) Field {
+	return Field{Key: "dao", Value: name}
+}
+
+// Method field of sql dao method name
+func Method(name string) Field {
+	return Field{Key: "method", Value: name}
+}
+
+// Key field of cache key or primary key
+func Key(key interface{}) Field {
+	return Field{Key: "key", Value: key}
+}
+
+// Args field of method args
+func Args(args interface{}) Field {
+	return Field{Key: "args", Value: args}
+}
+
+// Latency field of elapsed time since start
+func Latency(start time.Time) Field {
+	return Field{Key: "latency", Value: time.Since(start)}
+}
+
+// Err field of error
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// With wrap logger that logs with fields in addition
+func With(l Interface, fields ...Field) Interface {
+	if len(fields) == 0 {
+		return l
+	}
+	if fl, ok := l.(*fieldLogger); ok {
+		return &fieldLogger{Interface: fl.Interface, fields: append(append([]Field{}, fl.fields...), fields...)}
+	}
+	return &fieldLogger{Interface: l, fields: fields}
+}
+
+type fieldLogger struct {
+	Interface
+	fields []Field
+}
+
+func (l *fieldLogger) Debug(msg string, fields ...Field) {
+	l.Interface.Debug(msg, l.with(fields)...)
+}
+
+func (l *fieldLogger) Info(msg string, fields ...Field) {
+	l.Interface.Info(msg, l.with(fields)...)
+}
+
+func (l *fieldLogger) Warn(msg string, fields ...Field) {
+	l.Interface.Warn(msg, l.with(fields)...)
+}
+
+func (l *fieldLogger) Error(msg string, fields ...Field) {
+	l.Interface.Error(msg, l.with(fields)...)
+}
+
+func (l *fieldLogger) with(fields []Field) []Field {
+	return append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
 }
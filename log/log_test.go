@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	stdlog "log"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewStdLogger(stdlog.New(buf, "", 0), WarnLevel)
+
+	logger.Info("dropped", Key(1))
+	logger.Warn("missed cache", Dao("testUser"), Key(uint64(1)), Args([]interface{}{"alice", 1}))
+	logger.Error("set cache failed", Err(errors.New("set failed")), F("empty", ""))
+
+	want := "level=warning msg=\"missed cache\" dao=testUser key=1 args=\"[alice 1]\"\n" +
+		"level=error msg=\"set cache failed\" error=\"set failed\" empty=\"\"\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%swant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWith(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := With(With(NewStdLogger(stdlog.New(buf, "", 0), DebugLevel), Dao("testUser")), Method("GetByName"))
+	logger.Debug("hit cache", Key("k"))
+	if want := "level=debug msg=\"hit cache\" dao=testUser method=GetByName key=k\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
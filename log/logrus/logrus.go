@@ -0,0 +1,63 @@
+// Package logrus adapts logrus logger to the logger of cache dao, it's a package of its own,
+// so logrus isn't linked into the programs which don't use it.
+package logrus
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/zhyeah/gorm-cache/log"
+)
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// New adapt logrus logger (or entry) to 'log.Interface', fields are logged as logrus fields
+func New(logger logrus.FieldLogger) log.Interface {
+	return &logrusLogger{logger: logger}
+}
+
+func (l *logrusLogger) Debug(msg string, fields ...log.Field) {
+	if l.enabled(logrus.DebugLevel) {
+		l.entry(fields).Debug(msg)
+	}
+}
+
+func (l *logrusLogger) Info(msg string, fields ...log.Field) {
+	if l.enabled(logrus.InfoLevel) {
+		l.entry(fields).Info(msg)
+	}
+}
+
+func (l *logrusLogger) Warn(msg string, fields ...log.Field) {
+	if l.enabled(logrus.WarnLevel) {
+		l.entry(fields).Warn(msg)
+	}
+}
+
+func (l *logrusLogger) Error(msg string, fields ...log.Field) {
+	if l.enabled(logrus.ErrorLevel) {
+		l.entry(fields).Error(msg)
+	}
+}
+
+// enabled check level first, so the fields aren't converted for nothing
+func (l *logrusLogger) enabled(level logrus.Level) bool {
+	switch logger := l.logger.(type) {
+	case *logrus.Logger:
+		return logger.IsLevelEnabled(level)
+	case *logrus.Entry:
+		return logger.Logger.IsLevelEnabled(level)
+	}
+	return true
+}
+
+func (l *logrusLogger) entry(fields []log.Field) logrus.FieldLogger {
+	if len(fields) == 0 {
+		return l.logger
+	}
+	logrusFields := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		logrusFields[f.Key] = f.Value
+	}
+	return l.logger.WithFields(logrusFields)
+}
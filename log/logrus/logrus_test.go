@@ -0,0 +1,42 @@
+package logrus
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/zhyeah/gorm-cache/log"
+)
+
+func TestFields(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	err := errors.New("set failed")
+
+	for _, l := range []log.Interface{New(logger), New(logger.WithField("app", "test"))} {
+		hook.Reset()
+		l.Debug("dropped", log.Key(1))
+		l.Info("loaded", log.Dao("testUser"), log.Key(uint64(1)))
+		l.Error("set cache failed", log.Err(err))
+
+		entries := hook.AllEntries()
+		if len(entries) != 2 {
+			t.Fatalf("got %d entries, want 2", len(entries))
+		}
+		if entries[0].Level != logrus.InfoLevel || entries[0].Message != "loaded" {
+			t.Fatalf("got entry %v %s", entries[0].Level, entries[0].Message)
+		}
+		want := logrus.Fields{"dao": "testUser", "key": uint64(1)}
+		if _, ok := l.(*logrusLogger).logger.(*logrus.Entry); ok {
+			want["app"] = "test"
+		}
+		if !reflect.DeepEqual(entries[0].Data, want) {
+			t.Fatalf("got fields %v, want %v", entries[0].Data, want)
+		}
+		if entries[1].Level != logrus.ErrorLevel || entries[1].Data[logrus.ErrorKey] != err {
+			t.Fatalf("got entry %v %v", entries[1].Level, entries[1].Data)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapt 'log/slog' logger to Interface, fields are logged as attrs
+func NewSlogLogger(logger *slog.Logger) Interface {
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...Field) {
+	l.log(slog.LevelDebug, msg, fields)
+}
+
+func (l *slogLogger) Info(msg string, fields ...Field) {
+	l.log(slog.LevelInfo, msg, fields)
+}
+
+func (l *slogLogger) Warn(msg string, fields ...Field) {
+	l.log(slog.LevelWarn, msg, fields)
+}
+
+func (l *slogLogger) Error(msg string, fields ...Field) {
+	l.log(slog.LevelError, msg, fields)
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, fields []Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"strconv"
+	"strings"
+)
+
+// Level level of std logger
+type Level int
+
+// levels of std logger, from the lowest
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warning",
+	ErrorLevel: "error",
+}
+
+type stdLogger struct {
+	logger *stdlog.Logger
+	level  Level
+}
+
+// NewStdLogger adapt std 'log' logger to Interface, the logs below level are dropped,
+// fields are logged as 'key=value' after the message.
+func NewStdLogger(logger *stdlog.Logger, level Level) Interface {
+	return &stdLogger{logger: logger, level: level}
+}
+
+func (l *stdLogger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+func (l *stdLogger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+func (l *stdLogger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+func (l *stdLogger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+func (l *stdLogger) log(level Level, msg string, fields []Field) {
+	if level < l.level {
+		return
+	}
+	sb := &strings.Builder{}
+	sb.WriteString("level=")
+	sb.WriteString(levelNames[level])
+	sb.WriteString(" msg=")
+	sb.WriteString(quote(msg))
+	for _, f := range fields {
+		sb.WriteString(" ")
+		sb.WriteString(f.Key)
+		sb.WriteString("=")
+		sb.WriteString(quote(fmt.Sprint(f.Value)))
+	}
+	l.logger.Output(3, sb.String())
+}
+
+// quote quote the value if it's empty or has spaces, quotes or '='
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
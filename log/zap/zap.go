@@ -0,0 +1,53 @@
+// Package zap adapts zap logger to the logger of cache dao, it's a package of its own,
+// so zap isn't linked into the programs which don't use it.
+package zap
+
+import (
+	"github.com/zhyeah/gorm-cache/log"
+	"go.uber.org/zap"
+)
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// New adapt zap logger to 'log.Interface', fields are logged as 'zap.Any'
+func New(logger *zap.Logger) log.Interface {
+	return &zapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1))}
+}
+
+func (l *zapLogger) Debug(msg string, fields ...log.Field) {
+	if l.logger.Core().Enabled(zap.DebugLevel) {
+		l.logger.Debug(msg, zapFields(fields)...)
+	}
+}
+
+func (l *zapLogger) Info(msg string, fields ...log.Field) {
+	if l.logger.Core().Enabled(zap.InfoLevel) {
+		l.logger.Info(msg, zapFields(fields)...)
+	}
+}
+
+func (l *zapLogger) Warn(msg string, fields ...log.Field) {
+	if l.logger.Core().Enabled(zap.WarnLevel) {
+		l.logger.Warn(msg, zapFields(fields)...)
+	}
+}
+
+func (l *zapLogger) Error(msg string, fields ...log.Field) {
+	if l.logger.Core().Enabled(zap.ErrorLevel) {
+		l.logger.Error(msg, zapFields(fields)...)
+	}
+}
+
+func zapFields(fields []log.Field) []zap.Field {
+	zfs := make([]zap.Field, 0, len(fields))
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			zfs = append(zfs, zap.NamedError(f.Key, err))
+			continue
+		}
+		zfs = append(zfs, zap.Any(f.Key, f.Value))
+	}
+	return zfs
+}
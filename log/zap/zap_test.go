@@ -0,0 +1,42 @@
+package zap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zhyeah/gorm-cache/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := New(zap.New(core))
+	err := errors.New("set failed")
+
+	logger.Debug("dropped", log.Key(1))
+	logger.Info("loaded", log.Dao("testUser"), log.Key(uint64(1)), log.Args([]interface{}{"alice"}))
+	logger.Error("set cache failed", log.Method("GetByName"), log.Err(err))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Level != zapcore.InfoLevel || entries[0].Message != "loaded" {
+		t.Fatalf("got entry %v", entries[0].Entry)
+	}
+	want := map[string]interface{}{"dao": "testUser", "key": uint64(1), "args": []interface{}{"alice"}}
+	if got := entries[0].ContextMap(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got fields %v, want %v", got, want)
+	}
+	if entries[1].Level != zapcore.ErrorLevel || entries[1].Message != "set cache failed" {
+		t.Fatalf("got entry %v", entries[1].Entry)
+	}
+	// errors are logged as named errors
+	fields := entries[1].Context
+	if len(fields) != 2 || fields[1].Key != "error" || fields[1].Type != zapcore.ErrorType || fields[1].Interface != err {
+		t.Fatalf("got fields %v", fields)
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetrics prometheus implementation of 'core.Metrics', it's a collector as well,
+// register it by `prometheus.MustRegister(m)` and set it to 'core.DefaultMetrics' or 'CacheDaoBase.Metrics'.
+type PrometheusMetrics struct {
+	hits                *prometheus.CounterVec
+	misses              *prometheus.CounterVec
+	versionMisses       *prometheus.CounterVec
+	deserializeFailures *prometheus.CounterVec
+	dbFallbacks         *prometheus.CounterVec
+	setFailures         *prometheus.CounterVec
+	latency             *prometheus.HistogramVec
+}
+
+// NewPrometheusMetrics new prometheus metrics, the metric names are prefixed by namespace ('gormcache' if empty),
+// latency buckets are 'prometheus.DefBuckets' if not given.
+func NewPrometheusMetrics(namespace string, buckets ...float64) *PrometheusMetrics {
+	if namespace == "" {
+		namespace = "gormcache"
+	}
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	labels := []string{"dao", "method"}
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	}
+	return &PrometheusMetrics{
+		hits:                counter("hits_total", "Count of keys got from cache."),
+		misses:              counter("misses_total", "Count of keys absent in cache while the version is present."),
+		versionMisses:       counter("version_misses_total", "Count of keys whose version key is absent."),
+		deserializeFailures: counter("deserialize_failures_total", "Count of cached values failed to deserialize."),
+		dbFallbacks:         counter("db_fallbacks_total", "Count of keys loaded from database."),
+		setFailures:         counter("set_failures_total", "Count of failures to set cache."),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "latency_seconds",
+			Help:      "Latency of cached queries.",
+			Buckets:   buckets,
+		}, labels),
+	}
+}
+
+// Hit implement 'core.Metrics'
+func (m *PrometheusMetrics) Hit(dao, method string, n int) {
+	m.hits.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// Miss implement 'core.Metrics'
+func (m *PrometheusMetrics) Miss(dao, method string, n int) {
+	m.misses.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// VersionMiss implement 'core.Metrics'
+func (m *PrometheusMetrics) VersionMiss(dao, method string, n int) {
+	m.versionMisses.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// DeserializeFailure implement 'core.Metrics'
+func (m *PrometheusMetrics) DeserializeFailure(dao, method string, n int) {
+	m.deserializeFailures.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// DBFallback implement 'core.Metrics'
+func (m *PrometheusMetrics) DBFallback(dao, method string, n int) {
+	m.dbFallbacks.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// SetFailure implement 'core.Metrics'
+func (m *PrometheusMetrics) SetFailure(dao, method string, n int) {
+	m.setFailures.WithLabelValues(dao, method).Add(float64(n))
+}
+
+// Latency implement 'core.Metrics'
+func (m *PrometheusMetrics) Latency(dao, method string, d time.Duration) {
+	m.latency.WithLabelValues(dao, method).Observe(d.Seconds())
+}
+
+// Describe implement 'prometheus.Collector'
+func (m *PrometheusMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implement 'prometheus.Collector'
+func (m *PrometheusMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *PrometheusMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.hits, m.misses, m.versionMisses, m.deserializeFailures, m.dbFallbacks, m.setFailures, m.latency}
+}
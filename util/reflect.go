@@ -87,6 +87,19 @@ func RealTypeCheck(obj interface{}, kind reflect.Kind) bool {
 	return (objType.Kind() == kind)
 }
 
+// IsNil check if obj is nil or a nil pointer/slice/map/interface
+func IsNil(obj interface{}) bool {
+	if obj == nil {
+		return true
+	}
+	objValue := reflect.ValueOf(obj)
+	switch objValue.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		return objValue.IsNil()
+	}
+	return false
+}
+
 // GetRealTypeAndValue 获取对象真实的type和value
 func GetRealTypeAndValue(obj interface{}) (reflect.Type, reflect.Value) {
 	objType := reflect.TypeOf(obj)